@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Last Synced",type="date",JSONPath=`.status.lastSyncedTime`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// ClusterSopsSecret is the Schema for the clustersopssecrets API.
+// It distributes a Secret to the namespaces selected by its template.
+type ClusterSopsSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Type   corev1.SecretType `json:"type,omitempty"`
+	Spec   SopsSecretSpec    `json:"spec,omitempty"`
+	Data   string            `json:"data,omitempty"`
+	Status SopsSecretStatus  `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterSopsSecretList contains a list of ClusterSopsSecret
+type ClusterSopsSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterSopsSecret `json:"items"`
+}
+
+// GetSpec returns the spec of the ClusterSopsSecret.
+func (in *ClusterSopsSecret) GetSpec() *SopsSecretSpec {
+	return &in.Spec
+}
+
+// GetData returns the encrypted data of the ClusterSopsSecret.
+func (in *ClusterSopsSecret) GetData() string {
+	return in.Data
+}
+
+// GetSecretType returns the type of the generated Secret.
+func (in *ClusterSopsSecret) GetSecretType() corev1.SecretType {
+	return in.Type
+}
+
+// GetStatus returns the status of the ClusterSopsSecret.
+func (in *ClusterSopsSecret) GetStatus() *SopsSecretStatus {
+	return &in.Status
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterSopsSecret{}, &ClusterSopsSecretList{})
+}
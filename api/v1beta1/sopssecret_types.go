@@ -24,13 +24,101 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// Condition types reported on a SopsSecret.
+const (
+	// ReadyCondition is True when the data was decrypted and every target Secret is in sync.
+	ReadyCondition string = "Ready"
+	// DecryptedCondition reports whether the data field could be decrypted and parsed.
+	DecryptedCondition string = "Decrypted"
+	// SyncedCondition reports whether every target Secret matches the decrypted data.
+	SyncedCondition string = "Synced"
+	// ConflictCondition is True when a target Secret exists but is not owned by this object and may not be adopted.
+	ConflictCondition string = "Conflict"
+	// TargetsAllowedCondition is False when a SopsSecretPolicy or the controller configuration denies a target namespace.
+	TargetsAllowedCondition string = "TargetsAllowed"
+	// SuspendedCondition is True while spec.suspend stops the controller from writing any Secret.
+	SuspendedCondition string = "Suspended"
+	// ValidCondition is False when the spec can't be acted upon, e.g. a key pattern doesn't compile.
+	ValidCondition string = "Valid"
+	// RenderedCondition reports whether spec.template.data and spec.generator could be applied to the decrypted data.
+	RenderedCondition string = "Rendered"
+)
+
+// Condition and namespace status reasons.
+const (
+	ReasonSucceeded        string = "Succeeded"
+	ReasonDecryptionFailed string = "DecryptionFailed"
+	ReasonUnmarshalFailed  string = "UnmarshalFailed"
+	ReasonApplyFailed      string = "ApplyFailed"
+	ReasonNotOwned         string = "NotOwned"
+	ReasonOwnedByOther     string = "OwnedByOther"
+	ReasonNoConflict       string = "NoConflict"
+	ReasonSyncFailed       string = "SyncFailed"
+	ReasonTargetDenied     string = "TargetDenied"
+	ReasonSuspended        string = "Suspended"
+	ReasonInvalidSpec      string = "InvalidSpec"
+	ReasonTemplateFailed   string = "TemplateFailed"
+	ReasonGeneratorFailed  string = "GeneratorFailed"
+	ReasonInvalidSecret    string = "InvalidSecret"
+
+	// Decryption failures are classified by cause, only ReasonProviderUnavailable
+	// and ReasonDecryptionFailed are retried without a change to the object.
+	ReasonMACMismatch         string = "MACMismatch"
+	ReasonNoMatchingKey       string = "NoMatchingKey"
+	ReasonProviderUnavailable string = "ProviderUnavailable"
+	ReasonMalformedInput      string = "MalformedInput"
+)
+
+// SyncState is the outcome of reconciling a single target namespace.
+type SyncState string
+
+const (
+	// SyncStateSynced means the target Secret matches the decrypted data.
+	SyncStateSynced SyncState = "Synced"
+	// SyncStateSkipped means the target Secret exists but is not owned by the controller.
+	SyncStateSkipped SyncState = "Skipped"
+	// SyncStateFailed means the target Secret could not be written.
+	SyncStateFailed SyncState = "Failed"
+	// SyncStateDenied means writing to the target namespace is not allowed.
+	SyncStateDenied SyncState = "Denied"
+)
+
 // SopsSecretStatus defines the observed state of SopsSecret
 type SopsSecretStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the most recent generation reconciled by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastSyncedTime is the last time a target Secret was written.
+	LastSyncedTime *metav1.Time `json:"lastSyncedTime,omitempty"`
+
+	// LastHandledReconcileAt is the value of the reconcile-requested-at annotation last acted upon.
+	LastHandledReconcileAt string `json:"lastHandledReconcileAt,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Namespaces holds the outcome for each target namespace.
+	Namespaces []SopsSecretNamespaceStatus `json:"namespaces,omitempty"`
+}
+
+// SopsSecretNamespaceStatus is the observed state of the Secret in a single target namespace.
+type SopsSecretNamespaceStatus struct {
+	Namespace string    `json:"namespace"`
+	State     SyncState `json:"state"`
+	Reason    string    `json:"reason,omitempty"`
+	Message   string    `json:"message,omitempty"`
+
+	// LastSyncedTime is the last time the Secret in this namespace was written.
+	LastSyncedTime *metav1.Time `json:"lastSyncedTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Last Synced",type="date",JSONPath=`.status.lastSyncedTime`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 
 // SopsSecret is the Schema for the sopssecrets API
 type SopsSecret struct {
@@ -52,24 +140,251 @@ type SopsSecretList struct {
 	Items           []SopsSecret `json:"items"`
 }
 
+// AdoptionPolicy controls whether existing Secrets without the ownership label are taken over.
+// +kubebuilder:validation:Enum=Never;IfEmpty;Always
+type AdoptionPolicy string
+
+const (
+	// AdoptionPolicyNever leaves existing Secrets alone, this is the default.
+	AdoptionPolicyNever AdoptionPolicy = "Never"
+	// AdoptionPolicyIfEmpty only adopts existing Secrets without any data.
+	AdoptionPolicyIfEmpty AdoptionPolicy = "IfEmpty"
+	// AdoptionPolicyAlways adopts existing Secrets and overwrites their data.
+	AdoptionPolicyAlways AdoptionPolicy = "Always"
+)
+
+// OwnershipMode controls how generated Secrets are tied to the object they were generated from.
+// +kubebuilder:validation:Enum=Label;OwnerReference
+type OwnershipMode string
+
+const (
+	// OwnershipModeLabel only marks generated Secrets with the ownership label, the Secrets survive removal of the CRDs.
+	OwnershipModeLabel OwnershipMode = "Label"
+	// OwnershipModeOwnerReference additionally sets a controller owner reference where Kubernetes allows one,
+	// so the Secrets are garbage collected natively and show up in tools like kubectl tree.
+	OwnershipModeOwnerReference OwnershipMode = "OwnerReference"
+)
+
+// DeletionPolicy controls what happens to a generated Secret when the object it was generated from is deleted.
+// +kubebuilder:validation:Enum=Delete;Orphan;OrphanWithLabelRemoval
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the Secret, this is the default.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan leaves the Secret as is, a new object with the same name and namespace takes it over again.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+	// DeletionPolicyOrphanWithLabelRemoval leaves the Secret but strips the ownership label and annotations,
+	// handing it over to whoever manages it next.
+	DeletionPolicyOrphanWithLabelRemoval DeletionPolicy = "OrphanWithLabelRemoval"
+)
+
+// FlattenMode controls how nested maps and lists in the decrypted data are turned into Secret keys.
+// +kubebuilder:validation:Enum=None;Dotted;Underscore;JSON
+type FlattenMode string
+
+const (
+	// FlattenNone rejects nested values, this is the default.
+	FlattenNone FlattenMode = "None"
+	// FlattenDotted joins the path to every value with dots, e.g. `db.password` or `hosts.0`.
+	FlattenDotted FlattenMode = "Dotted"
+	// FlattenUnderscore joins the path to every value with underscores, e.g. `db_password` or `hosts_0`.
+	FlattenUnderscore FlattenMode = "Underscore"
+	// FlattenJSON stores every nested top-level value as a JSON document under its key.
+	FlattenJSON FlattenMode = "JSON"
+)
+
+// NullValues controls how null values in the decrypted data are stored.
+// +kubebuilder:validation:Enum=Empty;Omit;Reject
+type NullValues string
+
+const (
+	// NullValuesEmpty stores nulls as empty values, this is the default.
+	NullValuesEmpty NullValues = "Empty"
+	// NullValuesOmit leaves keys holding null out of the Secret.
+	NullValuesOmit NullValues = "Omit"
+	// NullValuesReject fails decryption when a null is found.
+	NullValuesReject NullValues = "Reject"
+)
+
+// ScalarValues controls how yaml and json scalars in the decrypted data are written to the Secret.
+// +kubebuilder:validation:Enum=Preserve;Normalize
+type ScalarValues string
+
+const (
+	// ScalarValuesPreserve stores every scalar as written in the file, e.g. `01234`, `yes` or `1.10`. This is the default.
+	ScalarValuesPreserve ScalarValues = "Preserve"
+	// ScalarValuesNormalize writes integers in base 10, floats in their shortest exact form and booleans as true or false.
+	ScalarValuesNormalize ScalarValues = "Normalize"
+)
+
+// Format is the SOPS file format of the data field.
+// +kubebuilder:validation:Enum=yaml;json;dotenv;ini;binary
+type Format string
+
+const (
+	// FormatYAML is a SOPS yaml document, this is the default.
+	FormatYAML Format = "yaml"
+	// FormatJSON is a SOPS json document.
+	FormatJSON Format = "json"
+	// FormatDotenv is a SOPS encrypted .env file of KEY=value lines.
+	FormatDotenv Format = "dotenv"
+	// FormatINI is a SOPS encrypted ini file, keys outside the default section are nested under their section.
+	FormatINI Format = "ini"
+	// FormatBinary is a whole file encrypted by SOPS, it requires fileKey.
+	FormatBinary Format = "binary"
+)
+
+// SopsSecretGenerator builds the data of a well-known Secret type from decrypted keys, only one generator may be set.
+// The decrypted keys read by the generator are replaced by its output.
+type SopsSecretGenerator struct {
+	// DockerConfigJSON produces the .dockerconfigjson key of a kubernetes.io/dockerconfigjson Secret.
+	DockerConfigJSON *DockerConfigJSONGenerator `json:"dockerConfigJson,omitempty"`
+	// BasicAuth produces the username and password keys of a kubernetes.io/basic-auth Secret.
+	BasicAuth *BasicAuthGenerator `json:"basicAuth,omitempty"`
+	// TLS produces the tls.crt, tls.key and optionally ca.crt keys of a kubernetes.io/tls Secret.
+	TLS *TLSGenerator `json:"tls,omitempty"`
+}
+
+type DockerConfigJSONGenerator struct {
+	// Registry is the server the credentials are for, e.g. ghcr.io.
+	Registry string `json:"registry"`
+	// UsernameKey is the decrypted key holding the username, username when empty.
+	UsernameKey string `json:"usernameKey,omitempty"`
+	// PasswordKey is the decrypted key holding the password or token, password when empty.
+	PasswordKey string `json:"passwordKey,omitempty"`
+	// EmailKey is the decrypted key holding the email address, left out when empty.
+	EmailKey string `json:"emailKey,omitempty"`
+}
+
+type BasicAuthGenerator struct {
+	// UsernameKey is the decrypted key holding the username, username when empty.
+	UsernameKey string `json:"usernameKey,omitempty"`
+	// PasswordKey is the decrypted key holding the password, password when empty.
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+type TLSGenerator struct {
+	// CertificateKey is the decrypted key holding the PEM leaf certificate, tls.crt when empty.
+	CertificateKey string `json:"certificateKey,omitempty"`
+	// IntermediateKeys are decrypted keys holding PEM intermediate certificates, appended to the leaf in order.
+	IntermediateKeys []string `json:"intermediateKeys,omitempty"`
+	// PrivateKeyKey is the decrypted key holding the PEM private key, tls.key when empty.
+	PrivateKeyKey string `json:"privateKeyKey,omitempty"`
+	// CAKey is the decrypted key holding the PEM CA certificate stored as ca.crt, left out when empty.
+	CAKey string `json:"caKey,omitempty"`
+}
+
 type SopsSecretSpec struct {
-	Template       SopsSecretTemplate `json:"template,omitempty"`
-	IgnoredKeys    []string           `json:"ignoredKeys,omitempty"`
-	SkipFinalizers bool               `json:"skipFinalizers,omitempty"`
+	Template SopsSecretTemplate `json:"template,omitempty"`
+
+	// IgnoredKeys are data keys whose live value is kept instead of the decrypted one.
+	// Entries match a key exactly, or as a pattern when prefixed with `glob:` or `regex:` (anchored).
+	IgnoredKeys []string `json:"ignoredKeys,omitempty"`
+
+	// ManagedKeys is the inverse of IgnoredKeys, only matching data keys are managed and all others are kept.
+	// Uses the same syntax as IgnoredKeys and can't be combined with it.
+	ManagedKeys []string `json:"managedKeys,omitempty"`
+
+	// SkipFinalizers is deprecated, it is equivalent to a deletionPolicy of Orphan.
+	SkipFinalizers bool `json:"skipFinalizers,omitempty"`
+
+	// DeletionPolicy applies to the target Secrets when this object is deleted.
+	// Overrides the --default-deletion-policy flag of the controller.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// NamespaceDeletionPolicies overrides the deletion policy for the Secret in individual target namespaces.
+	NamespaceDeletionPolicies map[string]DeletionPolicy `json:"namespaceDeletionPolicies,omitempty"`
+
+	// AdoptionPolicy controls whether existing Secrets without the ownership label are taken over.
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+
+	// RefreshInterval is how often the target Secrets are checked for drift without a watch event.
+	// Overrides the --resync-interval flag of the controller, 0 disables periodic checks.
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+
+	// BinaryKeys are data keys whose decrypted value is base64 and decoded before it is stored, e.g. keystores or keytabs.
+	// Uses the same syntax as IgnoredKeys.
+	BinaryKeys []string `json:"binaryKeys,omitempty"`
+
+	// ConfigMapKeys are data keys written to a ConfigMap next to the Secret instead of the Secret, e.g. non-sensitive settings.
+	// The ConfigMap has the name, namespaces, labels and deletion policy of the Secret. Uses the same syntax as IgnoredKeys.
+	ConfigMapKeys []string `json:"configMapKeys,omitempty"`
+
+	// ConfigMapUnencrypted also writes the keys SOPS left unencrypted because of the unencrypted_suffix of the data to the ConfigMap.
+	// Only supported for the yaml, json and dotenv formats.
+	ConfigMapUnencrypted bool `json:"configMapUnencrypted,omitempty"`
+
+	// Format is the SOPS file format of the data field, yaml when empty.
+	Format Format `json:"format,omitempty"`
+
+	// FileKey stores the whole decrypted file under this key instead of parsing it into keys and values.
+	// Required for the binary format, e.g. for a kubeconfig or application.properties.
+	FileKey string `json:"fileKey,omitempty"`
+
+	// Generator builds the data of a well-known Secret type from decrypted keys.
+	// The type of the Secret defaults to the one of the generator.
+	Generator *SopsSecretGenerator `json:"generator,omitempty"`
+
+	// Flatten controls how nested maps and lists in the decrypted data are turned into Secret keys.
+	Flatten FlattenMode `json:"flatten,omitempty"`
+
+	// NullValues controls how null values in the decrypted data are stored.
+	NullValues NullValues `json:"nullValues,omitempty"`
+
+	// ScalarValues controls how yaml and json scalars in the decrypted data are written to the Secret.
+	// Map keys are always kept as written.
+	ScalarValues ScalarValues `json:"scalarValues,omitempty"`
+
+	// Suspend stops the controller from writing, deleting or garbage collecting any of the target Secrets.
+	// Deleting a suspended object still applies its deletion policy.
+	Suspend bool `json:"suspend,omitempty"`
+
+	// OwnershipMode overrides the --default-ownership-mode flag of the controller.
+	// Owner references are only set on Secrets in the same namespace, other namespaces rely on the ownership label.
+	OwnershipMode OwnershipMode `json:"ownershipMode,omitempty"`
 }
 
 type SopsSecretTemplate struct {
 	SopsSecretTemplateMetadata `json:"metadata,omitempty"`
+
+	// Data maps Secret keys to Go templates rendered with the decrypted data, e.g. `{{ .user }}:{{ .password }}`.
+	// Rendered keys replace decrypted keys of the same name.
+	Data map[string]string `json:"data,omitempty"`
 }
 
 type SopsSecretTemplateMetadata struct {
 	Name       string   `json:"name,omitempty"`
 	Namespaces []string `json:"namespaces,omitempty"`
 
+	// NamespaceSelector selects additional target namespaces by label.
+	// Namespaces that stop matching have their Secret removed.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
 	Annotations map[string]string `json:"annotations,omitempty"`
 	Labels      map[string]string `json:"labels,omitempty"`
 }
 
+// GetSpec returns the spec of the SopsSecret.
+func (in *SopsSecret) GetSpec() *SopsSecretSpec {
+	return &in.Spec
+}
+
+// GetData returns the encrypted data of the SopsSecret.
+func (in *SopsSecret) GetData() string {
+	return in.Data
+}
+
+// GetSecretType returns the type of the generated Secret.
+func (in *SopsSecret) GetSecretType() corev1.SecretType {
+	return in.Type
+}
+
+// GetStatus returns the status of the SopsSecret.
+func (in *SopsSecret) GetStatus() *SopsSecretStatus {
+	return &in.Status
+}
+
 func init() {
 	SchemeBuilder.Register(&SopsSecret{}, &SopsSecretList{})
 }
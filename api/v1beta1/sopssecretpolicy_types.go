@@ -0,0 +1,58 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// SopsSecretPolicy is the Schema for the sopssecretpolicies API.
+// Once any policy exists, a SopsSecret may only write to namespaces other than its own when a rule allows it.
+type SopsSecretPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SopsSecretPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SopsSecretPolicyList contains a list of SopsSecretPolicy
+type SopsSecretPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SopsSecretPolicy `json:"items"`
+}
+
+// SopsSecretPolicySpec defines the cross namespace distribution allowed by a policy.
+type SopsSecretPolicySpec struct {
+	Rules []SopsSecretPolicyRule `json:"rules,omitempty"`
+}
+
+// SopsSecretPolicyRule allows SopsSecrets in the source namespaces to write to the target namespaces.
+// Entries are shell patterns, e.g. `team-a-*` or `*`.
+type SopsSecretPolicyRule struct {
+	SourceNamespaces []string `json:"sourceNamespaces"`
+	TargetNamespaces []string `json:"targetNamespaces"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SopsSecretPolicy{}, &SopsSecretPolicyList{})
+}
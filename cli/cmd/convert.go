@@ -18,10 +18,13 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/base64"
 	"errors"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"sort"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -65,7 +68,14 @@ var convertCmd = &cobra.Command{
 		}
 
 		tmpSecretData := make(map[string]string)
+		var binaryKeys []string
 		for k, v := range secret.Data {
+			// Values that aren't valid UTF-8 can't survive YAML, store them base64 encoded instead
+			if !utf8.Valid(v) {
+				tmpSecretData[k] = base64.StdEncoding.EncodeToString(v)
+				binaryKeys = append(binaryKeys, k)
+				continue
+			}
 			tmpSecretData[k] = string(v)
 		}
 
@@ -73,6 +83,8 @@ var convertCmd = &cobra.Command{
 		for k, v := range secret.StringData {
 			tmpSecretData[k] = v
 		}
+		binaryKeys = removeOverriddenKeys(binaryKeys, secret.StringData)
+		sort.Strings(binaryKeys)
 
 		secretData, err := yaml.Marshal(tmpSecretData)
 		if err != nil {
@@ -107,6 +119,7 @@ var convertCmd = &cobra.Command{
 		generatedSopsSecret.ObjectMeta = secret.ObjectMeta
 		generatedSopsSecret.Spec.Template.Annotations = secret.ObjectMeta.Annotations
 		generatedSopsSecret.Spec.Template.Labels = secret.ObjectMeta.Labels
+		generatedSopsSecret.Spec.BinaryKeys = binaryKeys
 		generatedSopsSecret.Data = sopsStdout.String()
 
 		// Set the GVK or YAMLPrinter doesn't work
@@ -125,6 +138,17 @@ var convertCmd = &cobra.Command{
 	},
 }
 
+// removeOverriddenKeys drops keys replaced by stringData, which always holds plain text.
+func removeOverriddenKeys(keys []string, stringData map[string]string) []string {
+	var kept []string
+	for _, key := range keys {
+		if _, ok := stringData[key]; !ok {
+			kept = append(kept, key)
+		}
+	}
+	return kept
+}
+
 func init() {
 	rootCmd.AddCommand(convertCmd)
 }
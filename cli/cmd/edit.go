@@ -112,8 +112,12 @@ var editCmd = &cobra.Command{
 		bytes.NewReader([]byte(sopsSecret.Data)).WriteTo(tmpfile)
 		tmpfile.Sync()
 
-		// Open sops editor directly
-		sopsCommand := exec.Command("sops", tmpfile.Name())
+		// Open sops editor directly, it guesses the format from the extension of the temporary file otherwise
+		sopsCommandArgs := []string{tmpfile.Name()}
+		if format := sopsSecret.Spec.Format; format != "" {
+			sopsCommandArgs = append([]string{"--input-type", string(format), "--output-type", string(format)}, sopsCommandArgs...)
+		}
+		sopsCommand := exec.Command("sops", sopsCommandArgs...)
 		sopsCommand.Stdin = os.Stdin
 		sopsCommand.Stdout = os.Stdout
 		sopsCommand.Stderr = os.Stderr
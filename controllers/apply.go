@@ -0,0 +1,105 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fieldManager owns the fields of generated Secrets written with server-side apply.
+const fieldManager = "sops-converter"
+
+// pruneSecret removes what server-side apply leaves in place because another manager owns it:
+// data keys added out of band and owner references from an earlier ownership mode.
+// Ignored keys are never applied, those missing from secret are filled in from ignored with a plain patch,
+// so the apply neither owns them nor removes them later. This also restores ignored keys an earlier version applied.
+func (r *SopsSecretReconciler) pruneSecret(ctx context.Context, secret *corev1.Secret, obj sopsSecretObject, data, ignored map[string][]byte, keys *keyMatcher, ownerReference bool) error {
+	base := secret.DeepCopy()
+	for key := range secret.Data {
+		if _, ok := data[key]; !ok && !keys.Ignored(key) {
+			delete(secret.Data, key)
+		}
+	}
+	for key, value := range ignored {
+		if _, ok := secret.Data[key]; ok {
+			continue
+		}
+		if secret.Data == nil {
+			secret.Data = make(map[string][]byte)
+		}
+		secret.Data[key] = value
+	}
+	if !ownerReference {
+		removeOwnerReference(secret, obj)
+	}
+
+	if equality.Semantic.DeepEqual(base, secret) {
+		return nil
+	}
+	return r.Patch(ctx, secret, client.MergeFrom(base))
+}
+
+// appliedExactly reports whether have contains every entry of want and the controller applied no other keys
+// of the metadata map field, e.g. a label since removed from the template.
+func appliedExactly(secret *corev1.Secret, field string, have, want map[string]string) bool {
+	for k, v := range want {
+		if existing, ok := have[k]; !ok || existing != v {
+			return false
+		}
+	}
+
+	applied, ok := appliedMetadataKeys(secret, field)
+	if !ok || len(applied) != len(want) {
+		return false
+	}
+	for k := range applied {
+		if _, ok := want[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// appliedMetadataKeys returns the keys of a metadata map field owned by the controller's apply operations.
+// Secrets never applied by the controller, e.g. written by an earlier version, report false.
+func appliedMetadataKeys(secret *corev1.Secret, field string) (map[string]bool, bool) {
+	for _, managedFields := range secret.ManagedFields {
+		if managedFields.Manager != fieldManager || managedFields.Operation != metav1.ManagedFieldsOperationApply || managedFields.FieldsV1 == nil {
+			continue
+		}
+
+		var fields struct {
+			Metadata map[string]map[string]interface{} `json:"f:metadata"`
+		}
+		if err := json.Unmarshal(managedFields.FieldsV1.Raw, &fields); err != nil {
+			return nil, false
+		}
+		keys := make(map[string]bool)
+		for key := range fields.Metadata[field] {
+			keys[strings.TrimPrefix(key, "f:")] = true
+		}
+		return keys, true
+	}
+	return nil, false
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// PlaintextCache is a bounded LRU cache of decrypted SopsSecret data keyed by the checksum of the encrypted data.
+// Entries expire after the configured TTL so rotated or revoked keys are noticed eventually.
+// Values are shared between callers and must be treated as read-only.
+type PlaintextCache struct {
+	maxEntries int
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type plaintextCacheEntry struct {
+	key       string
+	data      map[string]string
+	expiresAt time.Time
+}
+
+// NewPlaintextCache returns a cache holding at most maxEntries items for ttl each.
+// A nil cache is valid and never stores anything.
+func NewPlaintextCache(maxEntries int, ttl time.Duration) *PlaintextCache {
+	if maxEntries <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &PlaintextCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached data for key if present and not expired.
+func (c *PlaintextCache) Get(key string) (map[string]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*plaintextCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.data, true
+}
+
+// Set stores data under key, evicting the least recently used entry when full.
+func (c *PlaintextCache) Set(key string, data map[string]string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*plaintextCacheEntry)
+		entry.data = data
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&plaintextCacheEntry{
+		key:       key,
+		data:      data,
+		expiresAt: expiresAt,
+	})
+	for c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *PlaintextCache) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*plaintextCacheEntry).key)
+}
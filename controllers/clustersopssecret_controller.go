@@ -0,0 +1,114 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// ClusterSopsSecretReconciler reconciles a ClusterSopsSecret object.
+// It shares all of its logic with the SopsSecretReconciler, only the watched kind differs.
+type ClusterSopsSecretReconciler struct {
+	SopsSecretReconciler
+}
+
+// +kubebuilder:rbac:groups=secrets.dhouti.dev,resources=clustersopssecrets,verbs="*"
+// +kubebuilder:rbac:groups=secrets.dhouti.dev,resources=clustersopssecrets/status,verbs="*"
+// +kubebuilder:rbac:groups=secrets.dhouti.dev,resources=clustersopssecrets/finalizers,verbs=update
+
+func (r *ClusterSopsSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("clustersopssecret", req.Name)
+	// If not otherwise defined, default to the real decrypt func.
+	if r.Decryptor == nil {
+		realDecryptor := &SopsDecrytor{}
+		r.Decryptor = realDecryptor
+	}
+
+	// Attempt to fetch ClusterSopsSecret object. Short circuit if not exists
+	obj := &secretsv1beta1.ClusterSopsSecret{}
+	err := r.Get(ctx, req.NamespacedName, obj)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			err = nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	return r.reconcileObject(ctx, log, obj)
+}
+
+func (r *ClusterSopsSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := registerManagedSecretsCollector(mgr.GetClient())
+	if err != nil {
+		return err
+	}
+
+	enqueueOwner := handler.EnqueueRequestsFromMapFunc(
+		func(o client.Object) []reconcile.Request {
+			kind, owner, ok := ownerOf(o)
+			if !ok || kind != clusterSopsSecretKind {
+				return nil
+			}
+
+			return []reconcile.Request{
+				{
+					NamespacedName: owner,
+				},
+			}
+		},
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1beta1.ClusterSopsSecret{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, enqueueOwner).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, enqueueOwner).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(
+			func(o client.Object) []reconcile.Request {
+				clusterSopsSecretList := &secretsv1beta1.ClusterSopsSecretList{}
+				err := r.List(context.Background(), clusterSopsSecretList)
+				if err != nil {
+					r.Log.Error(err, "unable to list ClusterSopsSecrets for namespace event", "namespace", o.GetName())
+					return nil
+				}
+
+				var requests []reconcile.Request
+				for _, clusterSopsSecret := range clusterSopsSecretList.Items {
+					if clusterSopsSecret.Spec.Template.NamespaceSelector == nil {
+						continue
+					}
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{
+							Name: clusterSopsSecret.Name,
+						},
+					})
+				}
+				return requests
+			},
+		)).
+		Complete(r)
+}
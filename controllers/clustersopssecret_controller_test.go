@@ -0,0 +1,150 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+
+	. "github.com/onsi/gomega"
+
+	sopssecretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+	controllersmocks "github.com/dhouti/sops-converter/controllers/mocks"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("clustersopssecret controller", func() {
+	ctx := context.Background()
+	maxTimeout := 5
+	var clusterObjectName string
+	var targetNamespaces []string
+
+	BeforeEach(func() {
+		clusterObjectName = getRandomString()
+		targetNamespaces = []string{getRandomString(), getRandomString()}
+		for _, targetNamespace := range targetNamespaces {
+			createNamespace(targetNamespace)
+		}
+
+		usedClusterReconciler.InjectDecryptor(&controllersmocks.DecryptorMock{
+			DecryptFunc: func(input []byte, format string) ([]byte, error) {
+				return input, nil
+			},
+		})
+	})
+
+	AfterEach(func() {
+		currentObject := &sopssecretsv1beta1.ClusterSopsSecret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: clusterObjectName,
+			},
+		}
+		err := k8sClient.Delete(ctx, currentObject)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Name: clusterObjectName}, currentObject)
+		}, 30).Should(HaveOccurred())
+	})
+
+	It("distributes the secret to every target namespace", func() {
+		newSecret := &sopssecretsv1beta1.ClusterSopsSecret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: clusterObjectName,
+			},
+			Data: "secret: cluster",
+		}
+		newSecret.Spec.Template.Namespaces = targetNamespaces
+
+		err := k8sClient.Create(ctx, newSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		createdSecret := &corev1.Secret{}
+		for _, targetNamespace := range targetNamespaces {
+			createdSecretKey := types.NamespacedName{Name: clusterObjectName, Namespace: targetNamespace}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(Not(HaveOccurred()))
+			Expect(createdSecret.Data["secret"]).To(Equal([]byte("cluster")))
+		}
+
+		Eventually(func() bool {
+			fetchSecret := &sopssecretsv1beta1.ClusterSopsSecret{}
+			_ = k8sClient.Get(ctx, types.NamespacedName{Name: clusterObjectName}, fetchSecret)
+			return meta.IsStatusConditionTrue(fetchSecret.Status.Conditions, sopssecretsv1beta1.ReadyCondition)
+		}, maxTimeout).Should(BeTrue())
+	})
+
+	It("reports a spec without target namespaces as invalid", func() {
+		newSecret := &sopssecretsv1beta1.ClusterSopsSecret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: clusterObjectName,
+			},
+			Data: "secret: nowhere",
+		}
+
+		err := k8sClient.Create(ctx, newSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		fetchObject := &sopssecretsv1beta1.ClusterSopsSecret{}
+		Eventually(func() string {
+			_ = k8sClient.Get(ctx, types.NamespacedName{Name: clusterObjectName}, fetchObject)
+			condition := meta.FindStatusCondition(fetchObject.Status.Conditions, sopssecretsv1beta1.ValidCondition)
+			if condition == nil || condition.Status != metav1.ConditionFalse {
+				return ""
+			}
+			return condition.Reason
+		}, maxTimeout).Should(Equal(sopssecretsv1beta1.ReasonInvalidSpec))
+		Expect(meta.IsStatusConditionTrue(fetchObject.Status.Conditions, sopssecretsv1beta1.ReadyCondition)).To(BeFalse())
+	})
+
+	It("restores the secret when it is updated", func() {
+		newSecret := &sopssecretsv1beta1.ClusterSopsSecret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: clusterObjectName,
+			},
+			Data: "secret: cluster",
+		}
+		newSecret.Spec.Template.Namespaces = targetNamespaces[:1]
+
+		err := k8sClient.Create(ctx, newSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		createdSecretKey := types.NamespacedName{Name: clusterObjectName, Namespace: targetNamespaces[0]}
+		createdSecret := &corev1.Secret{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+		}, maxTimeout).Should(Not(HaveOccurred()))
+
+		createdSecret.Data["secret"] = []byte("changed")
+		err = k8sClient.Update(ctx, createdSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func() []byte {
+			err = k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			Expect(err).ToNot(HaveOccurred())
+			return createdSecret.Data["secret"]
+		}, maxTimeout).Should(Equal([]byte("cluster")))
+	})
+})
@@ -0,0 +1,225 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"go.mozilla.org/sops/v3/stores/dotenv"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// routesConfigMap reports whether spec sends any keys to a ConfigMap next to the Secret.
+func routesConfigMap(spec *secretsv1beta1.SopsSecretSpec) bool {
+	return len(spec.ConfigMapKeys) > 0 || spec.ConfigMapUnencrypted
+}
+
+// unencryptedSuffix reads the unencrypted_suffix from the sops metadata of the encrypted data of obj.
+// Data that can't be parsed has no suffix, decrypting it reports the actual problem.
+func unencryptedSuffix(obj sopsSecretObject) (string, error) {
+	data := []byte(obj.GetData())
+	switch format := obj.GetSpec().Format; format {
+	case "", secretsv1beta1.FormatYAML, secretsv1beta1.FormatJSON:
+		var document struct {
+			Sops struct {
+				UnencryptedSuffix string `yaml:"unencrypted_suffix"`
+			} `yaml:"sops"`
+		}
+		// Every document carries the same metadata, the first one is enough.
+		_ = yaml.NewDecoder(bytes.NewReader(data)).Decode(&document)
+		return document.Sops.UnencryptedSuffix, nil
+	case secretsv1beta1.FormatDotenv:
+		branches, err := (&dotenv.Store{}).LoadPlainFile(data)
+		if err != nil {
+			return "", nil
+		}
+		for _, item := range branches[0] {
+			if item.Key == dotenv.SopsPrefix+"unencrypted_suffix" {
+				suffix, _ := item.Value.(string)
+				return suffix, nil
+			}
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("configMapUnencrypted is not supported for format %s", format)
+	}
+}
+
+// splitConfigMapData separates the keys routed to the ConfigMap from those of the Secret, data itself is left untouched.
+func splitConfigMapData(keys *keyMatcher, data map[string]string) (map[string]string, map[string]string) {
+	secretData := make(map[string]string, len(data))
+	configMapData := make(map[string]string)
+	for key, value := range data {
+		if keys.routesConfigMap && keys.ConfigMap(key) {
+			configMapData[key] = value
+			continue
+		}
+		secretData[key] = value
+	}
+	return secretData, configMapData
+}
+
+// getConfigMap returns the ConfigMap at key, nil if there is none.
+func (r *SopsSecretReconciler) getConfigMap(ctx context.Context, key types.NamespacedName) (*corev1.ConfigMap, error) {
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, key, configMap)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return configMap, nil
+}
+
+// configMapConflict returns the reason an existing ConfigMap may not be written for obj, empty if it may.
+// ConfigMaps are adopted under the same adoptionPolicy as Secrets.
+func configMapConflict(obj sopsSecretObject, configMap *corev1.ConfigMap) string {
+	if configMap == nil || isOwnedBy(configMap, obj) {
+		return ""
+	}
+	if _, ok := configMap.Labels[OwnershipLabel]; ok {
+		return secretsv1beta1.ReasonOwnedByOther
+	}
+	if canAdopt(obj.GetSpec().AdoptionPolicy, len(configMap.Data) == 0 && len(configMap.BinaryData) == 0) {
+		return ""
+	}
+	return secretsv1beta1.ReasonNotOwned
+}
+
+// configMapChecksum hashes the data of a ConfigMap, both maps marshal deterministically.
+func configMapChecksum(data map[string]string, binaryData map[string][]byte) string {
+	dataBytes, _ := json.Marshal([]interface{}{data, binaryData})
+	return hashItem(dataBytes)
+}
+
+// configMapInSync reports whether configMap was written from the current payload and not modified since.
+func configMapInSync(configMap *corev1.ConfigMap, sopsChecksum string) bool {
+	if configMap == nil {
+		return false
+	}
+	return configMap.Annotations[SopsChecksumAnnotation] == sopsChecksum &&
+		configMap.Annotations[SecretChecksumAnotation] == configMapChecksum(configMap.Data, configMap.BinaryData)
+}
+
+// applyConfigMap writes the keys routed to the ConfigMap with server-side apply, carrying the labels and owner of the Secret.
+func (r *SopsSecretReconciler) applyConfigMap(ctx context.Context, obj sopsSecretObject, key types.NamespacedName, data map[string]string, labels map[string]string, sopsChecksum string, ownerReference bool) error {
+	// ConfigMap values must be UTF-8, decoded binary keys go to binaryData.
+	configMapData := make(map[string]string)
+	binaryData := make(map[string][]byte)
+	for k, v := range data {
+		if utf8.ValidString(v) {
+			configMapData[k] = v
+			continue
+		}
+		binaryData[k] = []byte(v)
+	}
+
+	annotations := make(map[string]string)
+	for k, v := range obj.GetSpec().Template.Annotations {
+		annotations[k] = v
+	}
+	for k, v := range ownerAnnotations(obj) {
+		annotations[k] = v
+	}
+	annotations[SopsChecksumAnnotation] = sopsChecksum
+	annotations[SecretChecksumAnotation] = configMapChecksum(configMapData, binaryData)
+
+	configMap := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        key.Name,
+			Namespace:   key.Namespace,
+			Annotations: annotations,
+			Labels:      labels,
+		},
+		Data:       configMapData,
+		BinaryData: binaryData,
+	}
+	if ownerReference {
+		err := controllerutil.SetControllerReference(obj, configMap, r.Scheme)
+		if err != nil {
+			return err
+		}
+	}
+	err := r.Patch(ctx, configMap, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+	if err != nil {
+		return err
+	}
+
+	// Same as pruneSecret, server-side apply leaves keys owned by other managers in place.
+	base := configMap.DeepCopy()
+	for k := range configMap.Data {
+		if _, ok := configMapData[k]; !ok {
+			delete(configMap.Data, k)
+		}
+	}
+	for k := range configMap.BinaryData {
+		if _, ok := binaryData[k]; !ok {
+			delete(configMap.BinaryData, k)
+		}
+	}
+	if !ownerReference {
+		removeOwnerReference(configMap, obj)
+	}
+	if equality.Semantic.DeepEqual(base, configMap) {
+		return nil
+	}
+	return r.Patch(ctx, configMap, client.MergeFrom(base))
+}
+
+// listOwnedConfigMaps returns every ConfigMap labelled as generated from obj.
+// ConfigMaps were never labelled in the legacy format.
+func (r *SopsSecretReconciler) listOwnedConfigMaps(ctx context.Context, obj sopsSecretObject) ([]corev1.ConfigMap, error) {
+	configMapList := &corev1.ConfigMapList{}
+	err := r.List(ctx, configMapList, client.MatchingLabels{
+		OwnershipLabel: ownershipLabelValue(obj),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return configMapList.Items, nil
+}
+
+// objectKind names the kind of a generated object in events and errors.
+func objectKind(obj client.Object) string {
+	if _, ok := obj.(*corev1.ConfigMap); ok {
+		return "configmap"
+	}
+	return "secret"
+}
+
+// isStaleConfigMap reports whether configMap is no longer wanted by obj, e.g. because its namespace or name is no longer targeted.
+func isStaleConfigMap(obj sopsSecretObject, configMap *corev1.ConfigMap, targetName string, targetNamespaces []string) bool {
+	return !routesConfigMap(obj.GetSpec()) || configMap.Name != targetName || !containsString(targetNamespaces, configMap.Namespace)
+}
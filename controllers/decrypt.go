@@ -0,0 +1,274 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.mozilla.org/sops/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// decryptError wraps a failure to turn the data of a SopsSecret into a valid Secret with the status reason it maps to.
+// Transient errors are retried with backoff, all others wait for the object to change.
+type decryptError struct {
+	Reason    string
+	Transient bool
+	Err       error
+}
+
+func (e *decryptError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *decryptError) Unwrap() error {
+	return e.Err
+}
+
+// decryptedData lazily decrypts the data of a SopsSecret, at most once per reconcile.
+// Reconciles where every target is already up to date never decrypt at all.
+type decryptedData struct {
+	load func() (map[string]string, error)
+
+	loaded bool
+	data   map[string]string
+	err    error
+}
+
+// Get returns the decrypted key/value pairs, decrypting on first use.
+func (d *decryptedData) Get() (map[string]string, error) {
+	if !d.loaded {
+		d.data, d.err = d.load()
+		d.loaded = true
+	}
+	return d.data, d.err
+}
+
+func (r *SopsSecretReconciler) newDecryptedData(log logr.Logger, obj sopsSecretObject) *decryptedData {
+	return &decryptedData{
+		load: func() (map[string]string, error) {
+			return r.decrypt(log, obj)
+		},
+	}
+}
+
+// payloadChecksum identifies the Secret and ConfigMap data obj produces.
+// The same data parses and renders differently depending on the spec, the settings are part of the checksum.
+func payloadChecksum(obj sopsSecretObject) string {
+	spec := obj.GetSpec()
+	// Objects using none of the payload settings keep the checksum of earlier releases, so upgrading doesn't rewrite their Secrets.
+	if !hasPayloadSettings(spec) {
+		return hashItem([]byte(obj.GetData()))
+	}
+	// Marshalling a struct of strings can't fail.
+	generator, _ := json.Marshal(spec.Generator)
+	return hashItem([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s\x00%q\x00%q\x00%s\x00%q\x00%t\x00%s",
+		spec.Format, spec.FileKey, spec.Flatten, spec.NullValues, spec.ScalarValues, spec.BinaryKeys, spec.Template.Data, generator,
+		spec.ConfigMapKeys, spec.ConfigMapUnencrypted, obj.GetData())))
+}
+
+// hasPayloadSettings reports whether spec changes how the data field is turned into Secret data.
+func hasPayloadSettings(spec *secretsv1beta1.SopsSecretSpec) bool {
+	return spec.Format != "" || spec.FileKey != "" || spec.Flatten != "" || spec.NullValues != "" || spec.ScalarValues != "" ||
+		len(spec.BinaryKeys) > 0 || len(spec.Template.Data) > 0 || spec.Generator != nil ||
+		len(spec.ConfigMapKeys) > 0 || spec.ConfigMapUnencrypted
+}
+
+// decrypt decrypts and parses the data field, consulting the plaintext cache first.
+func (r *SopsSecretReconciler) decrypt(log logr.Logger, obj sopsSecretObject) (map[string]string, error) {
+	// A requested reconcile always goes to the key provider, e.g. to pick up rotated grants.
+	forced := reconcileRequested(obj)
+
+	spec := obj.GetSpec()
+	cacheKey := payloadChecksum(obj)
+	if cached, ok := r.PlaintextCache.Get(cacheKey); ok && !forced {
+		return cached, nil
+	}
+
+	// Don't hammer the key provider with data that already failed for good, wait for the object to change.
+	if err := permanentDecryptFailure(obj); err != nil && !forced {
+		return nil, err
+	}
+
+	// Decrypt the Data field using Sops
+	decryptStart := time.Now()
+	unencryptedData, err := r.Decrypt([]byte(obj.GetData()), decryptFormat(spec))
+	decryptDuration.Observe(time.Since(decryptStart).Seconds())
+	if err != nil {
+		reason, transient := classifyDecryptError(err)
+		decryptFailures.WithLabelValues(decryptErrorClasses[reason]).Inc()
+		log.Error(err, "failed to decrypt data", "reason", reason, "transient", transient)
+		r.event(obj, corev1.EventTypeWarning, reason, "Failed to decrypt data: %v", err)
+		return nil, &decryptError{Reason: reason, Transient: transient, Err: err}
+	}
+
+	// Convert decryted secret into map[string]string, sadly cannot unmarshal directly into []byte
+	secretDataStrings, err := parsePayload(unencryptedData, spec)
+	if err != nil {
+		decryptFailures.WithLabelValues(decryptErrorClasses[secretsv1beta1.ReasonUnmarshalFailed]).Inc()
+		log.Error(err, "failed to unmarshal decrypted data")
+		r.event(obj, corev1.EventTypeWarning, secretsv1beta1.ReasonUnmarshalFailed, "Failed to unmarshal decrypted data: %v", err)
+		return nil, &decryptError{Reason: secretsv1beta1.ReasonUnmarshalFailed, Err: err}
+	}
+
+	err = renderDataTemplates(secretDataStrings, spec.Template.Data)
+	if err != nil {
+		decryptFailures.WithLabelValues(decryptErrorClasses[secretsv1beta1.ReasonTemplateFailed]).Inc()
+		log.Error(err, "failed to render template data")
+		r.event(obj, corev1.EventTypeWarning, secretsv1beta1.ReasonTemplateFailed, "Failed to render template data: %v", err)
+		return nil, &decryptError{Reason: secretsv1beta1.ReasonTemplateFailed, Err: err}
+	}
+
+	err = applyGenerator(secretDataStrings, spec.Generator)
+	if err != nil {
+		decryptFailures.WithLabelValues(decryptErrorClasses[secretsv1beta1.ReasonGeneratorFailed]).Inc()
+		log.Error(err, "failed to generate data")
+		r.event(obj, corev1.EventTypeWarning, secretsv1beta1.ReasonGeneratorFailed, "Failed to generate data: %v", err)
+		return nil, &decryptError{Reason: secretsv1beta1.ReasonGeneratorFailed, Err: err}
+	}
+
+	r.PlaintextCache.Set(cacheKey, secretDataStrings)
+	return secretDataStrings, nil
+}
+
+// Defaults for the backoff of transient decrypt failures.
+const (
+	defaultDecryptRetryBaseDelay = 5 * time.Second
+	defaultDecryptRetryMaxDelay  = 5 * time.Minute
+)
+
+func (r *SopsSecretReconciler) getDecryptBackoff() workqueue.RateLimiter {
+	r.decryptBackoffOnce.Do(func() {
+		baseDelay, maxDelay := r.DecryptRetryBaseDelay, r.DecryptRetryMaxDelay
+		if baseDelay <= 0 {
+			baseDelay = defaultDecryptRetryBaseDelay
+		}
+		if maxDelay <= 0 {
+			maxDelay = defaultDecryptRetryMaxDelay
+		}
+		r.decryptBackoff = workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay)
+	})
+	return r.decryptBackoff
+}
+
+// decryptFailureResult decides when obj is retried after failing to decrypt.
+// Transient failures back off exponentially, permanent ones are only retried once the object changes.
+func (r *SopsSecretReconciler) decryptFailureResult(log logr.Logger, obj sopsSecretObject, err *decryptError) ctrl.Result {
+	backoff := r.getDecryptBackoff()
+	if !err.Transient {
+		backoff.Forget(obj.GetUID())
+		log.Info("not retrying until the object changes", "reason", err.Reason)
+		return ctrl.Result{}
+	}
+
+	delay := backoff.When(obj.GetUID())
+	log.Info("retrying decryption", "reason", err.Reason, "after", delay)
+	return ctrl.Result{RequeueAfter: delay}
+}
+
+// Substrings of errors returned by key providers when they could not be reached or refused to serve the request for now.
+var providerUnavailableMessages = []string{
+	"connection refused",
+	"connection reset",
+	"no such host",
+	"i/o timeout",
+	"timed out",
+	"deadline exceeded",
+	"throttl",
+	"rate exceeded",
+	"too many requests",
+	"service unavailable",
+	"internal server error",
+	"requesterror",
+}
+
+// classifyDecryptError maps an error returned by the Decryptor to a status reason and whether retrying could help.
+// Sops only exposes most of its failures as strings, so this is best effort.
+func classifyDecryptError(err error) (string, bool) {
+	if errors.Is(err, sops.MetadataNotFound) {
+		return secretsv1beta1.ReasonMalformedInput, false
+	}
+
+	message := strings.ToLower(err.Error())
+	// The data key error only summarizes the key groups, the per key causes are in the user facing message.
+	var userErr interface{ UserError() string }
+	if errors.As(err, &userErr) {
+		message += "\n" + strings.ToLower(userErr.UserError())
+	}
+
+	switch {
+	case strings.Contains(message, "failed to verify data integrity"),
+		strings.Contains(message, "could not decrypt value"):
+		return secretsv1beta1.ReasonMACMismatch, false
+	case strings.Contains(message, "error unmarshalling input"),
+		strings.Contains(message, "error unmarshaling input"),
+		strings.Contains(message, "documents that are"):
+		return secretsv1beta1.ReasonMalformedInput, false
+	}
+
+	for _, providerMessage := range providerUnavailableMessages {
+		if strings.Contains(message, providerMessage) {
+			return secretsv1beta1.ReasonProviderUnavailable, true
+		}
+	}
+
+	if strings.Contains(message, "error getting data key") {
+		return secretsv1beta1.ReasonNoMatchingKey, false
+	}
+
+	// Unknown failures are retried, giving up on a misclassified transient error would need manual intervention.
+	return secretsv1beta1.ReasonDecryptionFailed, true
+}
+
+// isPermanentDecryptReason reports whether reason is a decrypt failure that is not retried until the object changes.
+func isPermanentDecryptReason(reason string) bool {
+	switch reason {
+	case secretsv1beta1.ReasonMACMismatch,
+		secretsv1beta1.ReasonNoMatchingKey,
+		secretsv1beta1.ReasonMalformedInput,
+		secretsv1beta1.ReasonUnmarshalFailed,
+		secretsv1beta1.ReasonTemplateFailed,
+		secretsv1beta1.ReasonGeneratorFailed:
+		return true
+	}
+	return false
+}
+
+// permanentDecryptFailure returns the recorded error if the current generation of obj already failed to decrypt for good.
+func permanentDecryptFailure(obj sopsSecretObject) error {
+	for _, conditionType := range []string{secretsv1beta1.DecryptedCondition, secretsv1beta1.RenderedCondition} {
+		condition := meta.FindStatusCondition(obj.GetStatus().Conditions, conditionType)
+		if condition == nil || condition.Status != metav1.ConditionFalse || condition.ObservedGeneration != obj.GetGeneration() {
+			continue
+		}
+		if isPermanentDecryptReason(condition.Reason) {
+			return &decryptError{Reason: condition.Reason, Err: errors.New(condition.Message)}
+		}
+	}
+	return nil
+}
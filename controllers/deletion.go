@@ -0,0 +1,215 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// deletionPolicy returns the policy applied to the Secret in namespace when obj is deleted.
+// A namespace override wins over the object's policy, which wins over the deprecated skipFinalizers and the controller default.
+func (r *SopsSecretReconciler) deletionPolicy(obj sopsSecretObject, namespace string) secretsv1beta1.DeletionPolicy {
+	spec := obj.GetSpec()
+	if policy := spec.NamespaceDeletionPolicies[namespace]; policy != "" {
+		return policy
+	}
+	if spec.DeletionPolicy != "" {
+		return spec.DeletionPolicy
+	}
+	if spec.SkipFinalizers {
+		return secretsv1beta1.DeletionPolicyOrphan
+	}
+	if r.DefaultDeletionPolicy != "" {
+		return r.DefaultDeletionPolicy
+	}
+	return secretsv1beta1.DeletionPolicyDelete
+}
+
+// needsFinalizer reports whether deleting obj requires any work on its target Secrets.
+// Secrets that are orphaned as is don't need the controller, so no finalizer holds up the deletion.
+func (r *SopsSecretReconciler) needsFinalizer(obj sopsSecretObject, targetNamespaces []string) bool {
+	for _, namespace := range targetNamespaces {
+		if r.deletionPolicy(obj, namespace) != secretsv1beta1.DeletionPolicyOrphan {
+			return true
+		}
+	}
+	return false
+}
+
+// release strips everything tying a generated Secret or ConfigMap to obj, so neither this controller nor the garbage collector touches it again.
+func (r *SopsSecretReconciler) release(ctx context.Context, generated client.Object, obj sopsSecretObject) error {
+	base := generated.DeepCopyObject().(client.Object)
+	labels := generated.GetLabels()
+	delete(labels, OwnershipLabel)
+	generated.SetLabels(labels)
+	annotations := generated.GetAnnotations()
+	for _, annotation := range []string{
+		OwnerKindAnnotation,
+		OwnerNameAnnotation,
+		OwnerNamespaceAnnotation,
+		SecretChecksumAnotation,
+		SopsChecksumAnnotation,
+		AdoptedChecksumAnnotation,
+	} {
+		delete(annotations, annotation)
+	}
+	generated.SetAnnotations(annotations)
+	removeOwnerReference(generated, obj)
+	return r.Patch(ctx, generated, client.MergeFrom(base))
+}
+
+// collectUntargeted applies the deletion policy of its namespace to a Secret or ConfigMap obj no longer writes,
+// the same way deleting obj would. Orphan leaves it exactly as it is.
+func (r *SopsSecretReconciler) collectUntargeted(ctx context.Context, obj sopsSecretObject, generated client.Object) error {
+	key := client.ObjectKeyFromObject(generated)
+	kind := objectKind(generated)
+	switch r.deletionPolicy(obj, generated.GetNamespace()) {
+	case secretsv1beta1.DeletionPolicyDelete:
+		err := r.Delete(ctx, generated)
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("deleting %s %s: %w", kind, key, err)
+		}
+		orphanDeletions.WithLabelValues(generated.GetNamespace()).Inc()
+		r.event(obj, corev1.EventTypeNormal, EventReasonGarbageCollected, "Deleted %s %s no longer targeted", kind, key)
+	case secretsv1beta1.DeletionPolicyOrphanWithLabelRemoval:
+		err := r.release(ctx, generated, obj)
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("releasing %s %s: %w", kind, key, err)
+		}
+		r.event(obj, corev1.EventTypeNormal, EventReasonOrphaned, "Released %s %s no longer targeted", kind, key)
+	}
+	return nil
+}
+
+// finalize applies the deletion policy to every Secret and ConfigMap generated from obj and only then releases the finalizer.
+// If any of them could not be handled the finalizer is kept, so the next attempt still finds all of them.
+func (r *SopsSecretReconciler) finalize(ctx context.Context, log logr.Logger, obj sopsSecretObject, targetName string, targetNamespaces []string) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(obj, DeletionFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	generated, err := r.objectsToFinalize(ctx, obj, targetName, targetNamespaces)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var errs []error
+	for _, object := range generated {
+		key := client.ObjectKeyFromObject(object)
+		kind := objectKind(object)
+		switch r.deletionPolicy(obj, object.GetNamespace()) {
+		case secretsv1beta1.DeletionPolicyDelete:
+			err = r.Delete(ctx, object)
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("deleting %s %s: %w", kind, key, err))
+				continue
+			}
+			r.event(obj, corev1.EventTypeNormal, EventReasonGarbageCollected, "Deleted %s %s", kind, key)
+		case secretsv1beta1.DeletionPolicyOrphanWithLabelRemoval:
+			err = r.release(ctx, object, obj)
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("releasing %s %s: %w", kind, key, err))
+				continue
+			}
+			r.event(obj, corev1.EventTypeNormal, EventReasonOrphaned, "Released %s %s", kind, key)
+		}
+	}
+	if len(errs) > 0 {
+		err = utilerrors.NewAggregate(errs)
+		log.Error(err, "failed to apply the deletion policy, keeping the finalizer")
+		return ctrl.Result{}, err
+	}
+
+	controllerutil.RemoveFinalizer(obj, DeletionFinalizer)
+	err = r.Update(ctx, obj)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, fmt.Errorf("unable to remove finalizer: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// objectsToFinalize returns the Secrets and ConfigMaps generated from obj, both those in the current target namespaces
+// and any others still carrying its ownership label, e.g. after the template name changed.
+func (r *SopsSecretReconciler) objectsToFinalize(ctx context.Context, obj sopsSecretObject, targetName string, targetNamespaces []string) ([]client.Object, error) {
+	var generated []client.Object
+	seen := make(map[types.NamespacedName]bool)
+	add := func(secret corev1.Secret) {
+		key := client.ObjectKeyFromObject(&secret)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		generated = append(generated, &secret)
+	}
+
+	for _, targetNamespace := range targetNamespaces {
+		secret := &corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: targetName, Namespace: targetNamespace}, secret)
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Secrets that were never adopted are not ours to delete.
+		if isOwnedBy(secret, obj) {
+			add(*secret)
+		}
+	}
+
+	ownedSecrets, err := r.listOwnedSecrets(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range ownedSecrets {
+		add(secret)
+	}
+
+	// ConfigMaps are always labelled, even when adopted.
+	ownedConfigMaps, err := r.listOwnedConfigMaps(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ownedConfigMaps {
+		generated = append(generated, &ownedConfigMaps[i])
+	}
+	return generated, nil
+}
@@ -0,0 +1,38 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Event reasons emitted by the controller, in addition to the failure reasons shared with status.
+const (
+	EventReasonSynced           string = "Synced"
+	EventReasonDriftRestored    string = "DriftRestored"
+	EventReasonGarbageCollected string = "GarbageCollected"
+	EventReasonAdopted          string = "Adopted"
+	EventReasonOrphaned         string = "Orphaned"
+)
+
+// event records an Event on obj if a recorder has been configured.
+func (r *SopsSecretReconciler) event(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
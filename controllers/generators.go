@@ -0,0 +1,254 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// tlsCAKey holds the CA certificate in kubernetes.io/tls Secrets, as written by cert-manager and others.
+const tlsCAKey = "ca.crt"
+
+// generatorSecretType returns the Secret type produced by generator, empty when none is set.
+func generatorSecretType(generator *secretsv1beta1.SopsSecretGenerator) corev1.SecretType {
+	switch {
+	case generator == nil:
+		return ""
+	case generator.DockerConfigJSON != nil:
+		return corev1.SecretTypeDockerConfigJson
+	case generator.BasicAuth != nil:
+		return corev1.SecretTypeBasicAuth
+	case generator.TLS != nil:
+		return corev1.SecretTypeTLS
+	}
+	return ""
+}
+
+// secretType is the type of the Secret generated for obj, defaulting to the type of its generator.
+func secretType(obj sopsSecretObject) corev1.SecretType {
+	if secretType := obj.GetSecretType(); secretType != "" {
+		return secretType
+	}
+	return generatorSecretType(obj.GetSpec().Generator)
+}
+
+// validateGenerator reports generator settings that can never produce a valid Secret.
+func validateGenerator(obj sopsSecretObject) error {
+	generator := obj.GetSpec().Generator
+	if generator == nil {
+		return nil
+	}
+
+	var set int
+	for _, isSet := range []bool{generator.DockerConfigJSON != nil, generator.BasicAuth != nil, generator.TLS != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("generator: exactly one of dockerConfigJson, basicAuth and tls must be set")
+	}
+	if generator.DockerConfigJSON != nil && generator.DockerConfigJSON.Registry == "" {
+		return errors.New("generator: dockerConfigJson.registry is required")
+	}
+
+	generatedType := generatorSecretType(generator)
+	if declaredType := obj.GetSecretType(); declaredType != "" && declaredType != generatedType {
+		return fmt.Errorf("generator: produces a Secret of type %s, but type is %s", generatedType, declaredType)
+	}
+	return nil
+}
+
+// applyGenerator replaces the keys read by generator with the keys it produces.
+func applyGenerator(data map[string]string, generator *secretsv1beta1.SopsSecretGenerator) error {
+	if generator == nil {
+		return nil
+	}
+
+	var generated map[string]string
+	var consumed []string
+	var err error
+	switch {
+	case generator.DockerConfigJSON != nil:
+		generated, consumed, err = generateDockerConfigJSON(data, generator.DockerConfigJSON)
+	case generator.BasicAuth != nil:
+		generated, consumed, err = generateBasicAuth(data, generator.BasicAuth)
+	case generator.TLS != nil:
+		generated, consumed, err = generateTLS(data, generator.TLS)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, key := range consumed {
+		delete(data, key)
+	}
+	for key, value := range generated {
+		data[key] = value
+	}
+	return nil
+}
+
+// generatorInput returns the decrypted value of key, or of defaultKey when key is empty.
+func generatorInput(data map[string]string, key, defaultKey string) (string, string, error) {
+	if key == "" {
+		key = defaultKey
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", key, fmt.Errorf("decrypted data has no key %q", key)
+	}
+	return value, key, nil
+}
+
+func generateDockerConfigJSON(data map[string]string, generator *secretsv1beta1.DockerConfigJSONGenerator) (map[string]string, []string, error) {
+	username, usernameKey, err := generatorInput(data, generator.UsernameKey, corev1.BasicAuthUsernameKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dockerConfigJson: %w", err)
+	}
+	password, passwordKey, err := generatorInput(data, generator.PasswordKey, corev1.BasicAuthPasswordKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dockerConfigJson: %w", err)
+	}
+	consumed := []string{usernameKey, passwordKey}
+
+	// The same shape kubectl create secret docker-registry produces.
+	type dockerConfigEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Email    string `json:"email,omitempty"`
+		Auth     string `json:"auth"`
+	}
+	entry := dockerConfigEntry{
+		Username: username,
+		Password: password,
+		Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+	if generator.EmailKey != "" {
+		entry.Email, _, err = generatorInput(data, generator.EmailKey, "")
+		if err != nil {
+			return nil, nil, fmt.Errorf("dockerConfigJson: %w", err)
+		}
+		consumed = append(consumed, generator.EmailKey)
+	}
+
+	dockerConfig, err := json.Marshal(map[string]map[string]dockerConfigEntry{
+		"auths": {generator.Registry: entry},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return map[string]string{corev1.DockerConfigJsonKey: string(dockerConfig)}, consumed, nil
+}
+
+func generateBasicAuth(data map[string]string, generator *secretsv1beta1.BasicAuthGenerator) (map[string]string, []string, error) {
+	username, usernameKey, err := generatorInput(data, generator.UsernameKey, corev1.BasicAuthUsernameKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("basicAuth: %w", err)
+	}
+	password, passwordKey, err := generatorInput(data, generator.PasswordKey, corev1.BasicAuthPasswordKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("basicAuth: %w", err)
+	}
+	generated := map[string]string{
+		corev1.BasicAuthUsernameKey: username,
+		corev1.BasicAuthPasswordKey: password,
+	}
+	return generated, []string{usernameKey, passwordKey}, nil
+}
+
+func generateTLS(data map[string]string, generator *secretsv1beta1.TLSGenerator) (map[string]string, []string, error) {
+	_, certificateKey, err := generatorInput(data, generator.CertificateKey, corev1.TLSCertKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls: %w", err)
+	}
+	privateKey, privateKeyKey, err := generatorInput(data, generator.PrivateKeyKey, corev1.TLSPrivateKeyKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls: %w", err)
+	}
+	consumed := []string{certificateKey, privateKeyKey}
+
+	// The chain is the leaf followed by the intermediates, every part normalized to end in a newline.
+	chain := &bytes.Buffer{}
+	for _, key := range append([]string{certificateKey}, generator.IntermediateKeys...) {
+		part, ok := data[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("tls: decrypted data has no key %q", key)
+		}
+		err = checkPEM(part, func(blockType string) bool { return blockType == "CERTIFICATE" })
+		if err != nil {
+			return nil, nil, fmt.Errorf("tls: key %q: %w", key, err)
+		}
+		chain.WriteString(strings.TrimSpace(part) + "\n")
+	}
+	consumed = append(consumed, generator.IntermediateKeys...)
+
+	err = checkPEM(privateKey, func(blockType string) bool { return strings.HasSuffix(blockType, "PRIVATE KEY") })
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls: key %q: %w", privateKeyKey, err)
+	}
+
+	generated := map[string]string{
+		corev1.TLSCertKey:       chain.String(),
+		corev1.TLSPrivateKeyKey: strings.TrimSpace(privateKey) + "\n",
+	}
+	if generator.CAKey != "" {
+		ca, _, err := generatorInput(data, generator.CAKey, "")
+		if err != nil {
+			return nil, nil, fmt.Errorf("tls: %w", err)
+		}
+		err = checkPEM(ca, func(blockType string) bool { return blockType == "CERTIFICATE" })
+		if err != nil {
+			return nil, nil, fmt.Errorf("tls: key %q: %w", generator.CAKey, err)
+		}
+		generated[tlsCAKey] = strings.TrimSpace(ca) + "\n"
+		consumed = append(consumed, generator.CAKey)
+	}
+	return generated, consumed, nil
+}
+
+// checkPEM verifies value consists of at least one PEM block, all of a type accepted by validType.
+func checkPEM(value string, validType func(string) bool) error {
+	rest := []byte(strings.TrimSpace(value))
+	var blocks int
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return errors.New("not PEM encoded")
+		}
+		if !validType(block.Type) {
+			return fmt.Errorf("unexpected PEM block %s", block.Type)
+		}
+		blocks++
+		rest = bytes.TrimSpace(rest)
+	}
+	if blocks == 0 {
+		return errors.New("no PEM block found")
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Prefixes of key patterns, entries without one match a key exactly.
+const (
+	globKeyPrefix  = "glob:"
+	regexKeyPrefix = "regex:"
+)
+
+// keyPattern matches Secret data keys by exact name, shell glob or regular expression.
+type keyPattern struct {
+	exact string
+	glob  string
+	regex *regexp.Regexp
+}
+
+func compileKeyPattern(pattern string) (keyPattern, error) {
+	switch {
+	case strings.HasPrefix(pattern, globKeyPrefix):
+		glob := strings.TrimPrefix(pattern, globKeyPrefix)
+		// path.Match only reports a malformed pattern while matching.
+		if _, err := path.Match(glob, ""); err != nil {
+			return keyPattern{}, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+		return keyPattern{glob: glob}, nil
+	case strings.HasPrefix(pattern, regexKeyPrefix):
+		// Anchored, so a pattern never matches just part of a key by accident.
+		regex, err := regexp.Compile("^(?:" + strings.TrimPrefix(pattern, regexKeyPrefix) + ")$")
+		if err != nil {
+			return keyPattern{}, fmt.Errorf("invalid regex %q: %w", strings.TrimPrefix(pattern, regexKeyPrefix), err)
+		}
+		return keyPattern{regex: regex}, nil
+	default:
+		return keyPattern{exact: pattern}, nil
+	}
+}
+
+func (p keyPattern) matches(key string) bool {
+	switch {
+	case p.regex != nil:
+		return p.regex.MatchString(key)
+	case p.glob != "":
+		matched, _ := path.Match(p.glob, key)
+		return matched
+	default:
+		return p.exact == key
+	}
+}
+
+// keyMatcher decides which data keys of a target Secret are left to other writers and which go to the ConfigMap instead.
+type keyMatcher struct {
+	ignored []keyPattern
+	managed []keyPattern
+
+	configMap         []keyPattern
+	unencryptedSuffix string
+	routesConfigMap   bool
+}
+
+// newKeyMatcher compiles the key patterns of obj, ignoredKeys and managedKeys can't be combined.
+func newKeyMatcher(obj sopsSecretObject) (*keyMatcher, error) {
+	spec := obj.GetSpec()
+	if len(spec.IgnoredKeys) > 0 && len(spec.ManagedKeys) > 0 {
+		return nil, errors.New("ignoredKeys and managedKeys are mutually exclusive")
+	}
+
+	ignored, err := compileKeyPatterns("ignoredKeys", spec.IgnoredKeys)
+	if err != nil {
+		return nil, err
+	}
+	managed, err := compileKeyPatterns("managedKeys", spec.ManagedKeys)
+	if err != nil {
+		return nil, err
+	}
+	// Only used while parsing the decrypted data, compiled here so a typo is reported before decrypting.
+	_, err = compileKeyPatterns("binaryKeys", spec.BinaryKeys)
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := compileKeyPatterns("configMapKeys", spec.ConfigMapKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := &keyMatcher{
+		ignored:         ignored,
+		managed:         managed,
+		configMap:       configMap,
+		routesConfigMap: routesConfigMap(spec),
+	}
+	if spec.ConfigMapUnencrypted {
+		matcher.unencryptedSuffix, err = unencryptedSuffix(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matcher, nil
+}
+
+func compileKeyPatterns(field string, patterns []string) ([]keyPattern, error) {
+	compiled := make([]keyPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		keyPattern, err := compileKeyPattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		compiled = append(compiled, keyPattern)
+	}
+	return compiled, nil
+}
+
+// Ignored reports whether the live value of key is kept instead of the decrypted one.
+func (m *keyMatcher) Ignored(key string) bool {
+	if len(m.managed) > 0 {
+		return !matchesAnyKeyPattern(m.managed, key)
+	}
+	return matchesAnyKeyPattern(m.ignored, key)
+}
+
+// Unmanaged reports whether key is outside managedKeys, its decrypted value is then never written.
+func (m *keyMatcher) Unmanaged(key string) bool {
+	return len(m.managed) > 0 && !matchesAnyKeyPattern(m.managed, key)
+}
+
+// ManagedData returns the entries of data whose keys the controller writes, ignored keys belong to other writers.
+func (m *keyMatcher) ManagedData(data map[string][]byte) map[string][]byte {
+	managed := make(map[string][]byte, len(data))
+	for key, value := range data {
+		if !m.Ignored(key) {
+			managed[key] = value
+		}
+	}
+	return managed
+}
+
+// ConfigMap reports whether key is written to the ConfigMap instead of the Secret.
+func (m *keyMatcher) ConfigMap(key string) bool {
+	if m.unencryptedSuffix != "" && strings.HasSuffix(key, m.unencryptedSuffix) {
+		return true
+	}
+	return matchesAnyKeyPattern(m.configMap, key)
+}
+
+func matchesAnyKeyPattern(patterns []keyPattern, key string) bool {
+	for _, pattern := range patterns {
+		if pattern.matches(key) {
+			return true
+		}
+	}
+	return false
+}
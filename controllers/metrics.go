@@ -0,0 +1,127 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+const metricsNamespace = "sops_converter"
+
+// decryptErrorClasses maps decrypt failure reasons to the class label of the decrypt failure metric.
+var decryptErrorClasses = map[string]string{
+	secretsv1beta1.ReasonDecryptionFailed:    "decrypt",
+	secretsv1beta1.ReasonUnmarshalFailed:     "unmarshal",
+	secretsv1beta1.ReasonMACMismatch:         "mac_mismatch",
+	secretsv1beta1.ReasonNoMatchingKey:       "no_matching_key",
+	secretsv1beta1.ReasonProviderUnavailable: "provider_unavailable",
+	secretsv1beta1.ReasonMalformedInput:      "malformed_input",
+	secretsv1beta1.ReasonTemplateFailed:      "template",
+	secretsv1beta1.ReasonGeneratorFailed:     "generator",
+}
+
+var (
+	decryptDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "decrypt_duration_seconds",
+		Help:      "Time taken to decrypt the data of a SopsSecret.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	})
+
+	decryptFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "decrypt_failures_total",
+		Help:      "Number of failed attempts to decrypt, parse, render or generate SopsSecret data, by error class.",
+	}, []string{"class"})
+
+	driftRestorations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "drift_restorations_total",
+		Help:      "Number of managed Secrets restored after being modified out of band.",
+	}, []string{"namespace"})
+
+	skippedUnowned = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "skipped_unowned_total",
+		Help:      "Number of times a target Secret or ConfigMap was skipped because it is not owned by the SopsSecret, by status reason.",
+	}, []string{"namespace", "reason"})
+
+	orphanDeletions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphan_deletions_total",
+		Help:      "Number of managed Secrets and ConfigMaps deleted because they are no longer targeted or their owner no longer exists.",
+	}, []string{"namespace"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		decryptDuration,
+		decryptFailures,
+		driftRestorations,
+		skippedUnowned,
+		orphanDeletions,
+	)
+}
+
+var managedSecretsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(metricsNamespace, "", "managed_secrets"),
+	"Number of Secrets carrying the ownership label, by namespace.",
+	[]string{"namespace"}, nil,
+)
+
+// managedSecretsCollector counts labelled Secrets at scrape time so the value can never drift from the cluster.
+type managedSecretsCollector struct {
+	client.Reader
+}
+
+func (c *managedSecretsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- managedSecretsDesc
+}
+
+func (c *managedSecretsCollector) Collect(ch chan<- prometheus.Metric) {
+	secretList := &corev1.SecretList{}
+	err := c.List(context.Background(), secretList, client.HasLabels{OwnershipLabel})
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(managedSecretsDesc, err)
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, secret := range secretList.Items {
+		counts[secret.Namespace]++
+	}
+	for namespace, count := range counts {
+		ch <- prometheus.MustNewConstMetric(managedSecretsDesc, prometheus.GaugeValue, float64(count), namespace)
+	}
+}
+
+// registerManagedSecretsCollector registers the collector once, reusing an existing registration.
+func registerManagedSecretsCollector(reader client.Reader) error {
+	err := metrics.Registry.Register(&managedSecretsCollector{Reader: reader})
+	if errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// OrphanPolicy controls what the OrphanCollector does with Secrets and ConfigMaps whose owner no longer exists.
+type OrphanPolicy string
+
+const (
+	// OrphanPolicyReport only logs and records an Event on the orphaned Secrets.
+	OrphanPolicyReport OrphanPolicy = "Report"
+	// OrphanPolicyDelete deletes the orphaned Secrets.
+	OrphanPolicyDelete OrphanPolicy = "Delete"
+)
+
+// EventReasonOrphanDetected is recorded on Secrets and ConfigMaps whose owner no longer exists.
+const EventReasonOrphanDetected string = "OrphanDetected"
+
+var _ manager.Runnable = &OrphanCollector{}
+var _ manager.LeaderElectionRunnable = &OrphanCollector{}
+
+// OrphanCollector finds Secrets and ConfigMaps carrying the ownership label whose SopsSecret or ClusterSopsSecret no longer exists.
+// This happens when the owner is deleted while the controller is down or without a finalizer, the watches never see it.
+// It sweeps once on start and then every Interval.
+type OrphanCollector struct {
+	client.Client
+	// APIReader looks up owners bypassing the cache, a Secret may be seen before the object it was just generated from.
+	APIReader client.Reader
+	Log       logr.Logger
+	Recorder  record.EventRecorder
+
+	// Policy is what happens to the orphaned Secrets, Report when empty.
+	Policy OrphanPolicy
+	// Interval between sweeps after the first one, only the startup sweep runs when 0.
+	Interval time.Duration
+	// DryRun logs what would be deleted without deleting anything.
+	DryRun bool
+}
+
+// Start runs the sweeps until ctx is done.
+func (c *OrphanCollector) Start(ctx context.Context) error {
+	if c.Interval <= 0 {
+		c.sweepAndLog(ctx)
+		return nil
+	}
+	wait.UntilWithContext(ctx, c.sweepAndLog, c.Interval)
+	return nil
+}
+
+// NeedLeaderElection makes sure only one replica deletes Secrets.
+func (c *OrphanCollector) NeedLeaderElection() bool {
+	return true
+}
+
+func (c *OrphanCollector) sweepAndLog(ctx context.Context) {
+	if err := c.Sweep(ctx); err != nil {
+		c.Log.Error(err, "orphaned secret sweep failed")
+	}
+}
+
+// Sweep handles every Secret and ConfigMap carrying the ownership label whose owner no longer exists according to the policy.
+func (c *OrphanCollector) Sweep(ctx context.Context) error {
+	secretList := &corev1.SecretList{}
+	err := c.List(ctx, secretList, client.HasLabels{OwnershipLabel})
+	if err != nil {
+		return err
+	}
+	configMapList := &corev1.ConfigMapList{}
+	err = c.List(ctx, configMapList, client.HasLabels{OwnershipLabel})
+	if err != nil {
+		return err
+	}
+
+	generated := make([]client.Object, 0, len(secretList.Items)+len(configMapList.Items))
+	for i := range secretList.Items {
+		generated = append(generated, &secretList.Items[i])
+	}
+	for i := range configMapList.Items {
+		generated = append(generated, &configMapList.Items[i])
+	}
+
+	for _, object := range generated {
+		kind := objectKind(object)
+		log := c.Log.WithValues(kind, client.ObjectKeyFromObject(object))
+
+		orphaned, err := c.isOrphaned(ctx, object)
+		if err != nil {
+			log.Error(err, "unable to look up owner")
+			continue
+		}
+		if !orphaned {
+			continue
+		}
+
+		if c.Policy != OrphanPolicyDelete {
+			log.Info("found orphaned " + kind)
+			c.event(object, corev1.EventTypeWarning, EventReasonOrphanDetected, "The object this %s was generated from no longer exists", kind)
+			continue
+		}
+		if c.DryRun {
+			log.Info("would delete orphaned " + kind + " (dry run)")
+			continue
+		}
+
+		err = c.Delete(ctx, object)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			log.Error(err, "failed to delete orphaned "+kind)
+			continue
+		}
+		log.Info("deleted orphaned " + kind)
+		orphanDeletions.WithLabelValues(object.GetNamespace()).Inc()
+	}
+	return nil
+}
+
+// isOrphaned reports whether the owner recorded on a generated object is gone.
+// Objects whose owner can't be determined are never considered orphaned.
+func (c *OrphanCollector) isOrphaned(ctx context.Context, generated client.Object) (bool, error) {
+	kind, ownerKey, ok := ownerOf(generated)
+	if !ok {
+		return false, nil
+	}
+
+	var owner client.Object
+	switch kind {
+	case sopsSecretKind:
+		owner = &secretsv1beta1.SopsSecret{}
+	case clusterSopsSecretKind:
+		owner = &secretsv1beta1.ClusterSopsSecret{}
+	default:
+		return false, nil
+	}
+
+	err := c.APIReader.Get(ctx, ownerKey, owner)
+	if k8serrors.IsNotFound(err) {
+		return true, nil
+	}
+	return false, err
+}
+
+func (c *OrphanCollector) event(obj client.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if c.Recorder == nil {
+		return
+	}
+	c.Recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
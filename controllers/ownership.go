@@ -0,0 +1,203 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// Annotations recording the owner of a generated Secret.
+// The ownership label only holds a hash so it fits the 63 character limit, these are used to find the owner again.
+const OwnerKindAnnotation string = "secrets.dhouti.dev/owner-kind"
+const OwnerNameAnnotation string = "secrets.dhouti.dev/owner-name"
+const OwnerNamespaceAnnotation string = "secrets.dhouti.dev/owner-namespace"
+
+const (
+	sopsSecretKind        string = "SopsSecret"
+	clusterSopsSecretKind string = "ClusterSopsSecret"
+)
+
+// kindOf returns the kind of obj, typed objects read through the client have an empty TypeMeta.
+func kindOf(obj sopsSecretObject) string {
+	if _, ok := obj.(*secretsv1beta1.ClusterSopsSecret); ok {
+		return clusterSopsSecretKind
+	}
+	return sopsSecretKind
+}
+
+// ownershipLabelValue returns the value of the ownership label for Secrets generated from obj.
+// It is a hash of the kind, namespace and name of obj so any name is supported.
+func ownershipLabelValue(obj sopsSecretObject) string {
+	return hashItem([]byte(fmt.Sprintf("%s/%s/%s", kindOf(obj), obj.GetNamespace(), obj.GetName())))
+}
+
+// legacyOwnershipLabelValue returns the ${Name}.${Namespace} value used before owner annotations existed.
+// Secrets carrying it are relabelled the next time they are reconciled.
+func legacyOwnershipLabelValue(obj sopsSecretObject) string {
+	return fmt.Sprintf("%s.%s", obj.GetName(), obj.GetNamespace())
+}
+
+func ownerAnnotations(obj sopsSecretObject) map[string]string {
+	annotations := map[string]string{
+		OwnerKindAnnotation: kindOf(obj),
+		OwnerNameAnnotation: obj.GetName(),
+	}
+	if obj.GetNamespace() != "" {
+		annotations[OwnerNamespaceAnnotation] = obj.GetNamespace()
+	}
+	return annotations
+}
+
+// isOwnedBy reports whether the ownership label of secret points at obj, in either the current or the legacy format.
+func isOwnedBy(secret client.Object, obj sopsSecretObject) bool {
+	value, ok := secret.GetLabels()[OwnershipLabel]
+	if !ok {
+		return false
+	}
+	return value == ownershipLabelValue(obj) || value == legacyOwnershipLabelValue(obj)
+}
+
+// ownerOf returns the kind and key of the object that generated secret.
+// Secrets without owner annotations fall back to parsing the legacy label value.
+func ownerOf(secret client.Object) (string, types.NamespacedName, bool) {
+	labels := secret.GetLabels()
+	ownershipLabel, ok := labels[OwnershipLabel]
+	if !ok {
+		return "", types.NamespacedName{}, false
+	}
+
+	annotations := secret.GetAnnotations()
+	if name, ok := annotations[OwnerNameAnnotation]; ok {
+		kind := annotations[OwnerKindAnnotation]
+		if kind == "" {
+			kind = sopsSecretKind
+		}
+		return kind, types.NamespacedName{
+			Name:      name,
+			Namespace: annotations[OwnerNamespaceAnnotation],
+		}, true
+	}
+
+	splitOwnershipLabel := strings.Split(ownershipLabel, ".")
+	if len(splitOwnershipLabel) != 2 {
+		return "", types.NamespacedName{}, false
+	}
+
+	// Only ClusterSopsSecrets lack a namespace.
+	kind := sopsSecretKind
+	if splitOwnershipLabel[1] == "" {
+		kind = clusterSopsSecretKind
+	}
+	return kind, types.NamespacedName{
+		Name:      splitOwnershipLabel[0],
+		Namespace: splitOwnershipLabel[1],
+	}, true
+}
+
+// listOwnedSecrets returns every Secret labelled as generated from obj, including those still carrying the legacy label value.
+func (r *SopsSecretReconciler) listOwnedSecrets(ctx context.Context, obj sopsSecretObject) ([]corev1.Secret, error) {
+	labelValues := []string{ownershipLabelValue(obj)}
+	// Names that don't make a valid label value can't have been labelled in the legacy format.
+	legacyValue := legacyOwnershipLabelValue(obj)
+	if len(validation.IsValidLabelValue(legacyValue)) == 0 {
+		labelValues = append(labelValues, legacyValue)
+	}
+
+	var ownedSecrets []corev1.Secret
+	for _, labelValue := range labelValues {
+		secretList := &corev1.SecretList{}
+		err := r.List(ctx, secretList, client.MatchingLabels{
+			OwnershipLabel: labelValue,
+		})
+		if err != nil {
+			return nil, err
+		}
+		ownedSecrets = append(ownedSecrets, secretList.Items...)
+	}
+	return ownedSecrets, nil
+}
+
+// ownershipMode returns the ownership mode of obj, falling back to the controller default.
+func (r *SopsSecretReconciler) ownershipMode(obj sopsSecretObject) secretsv1beta1.OwnershipMode {
+	if mode := obj.GetSpec().OwnershipMode; mode != "" {
+		return mode
+	}
+	if r.DefaultOwnershipMode != "" {
+		return r.DefaultOwnershipMode
+	}
+	return secretsv1beta1.OwnershipModeLabel
+}
+
+// wantsOwnerReference reports whether the Secret generated from obj in namespace should carry a controller reference to obj.
+// Kubernetes would garbage collect the Secret along with obj, so none is set when the Secret is meant to outlive obj.
+func (r *SopsSecretReconciler) wantsOwnerReference(obj sopsSecretObject, namespace string, deletionPolicy secretsv1beta1.DeletionPolicy) bool {
+	if r.ownershipMode(obj) != secretsv1beta1.OwnershipModeOwnerReference || deletionPolicy != secretsv1beta1.DeletionPolicyDelete {
+		return false
+	}
+	// Owner references can't cross namespaces, cluster scoped owners may own objects in any namespace.
+	return obj.GetNamespace() == "" || obj.GetNamespace() == namespace
+}
+
+// hasOwnerReference reports whether secret carries an owner reference to obj.
+func hasOwnerReference(secret client.Object, obj sopsSecretObject) bool {
+	for _, ownerReference := range secret.GetOwnerReferences() {
+		if ownerReference.UID == obj.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// removeOwnerReference drops any owner reference to obj from secret, switching it back to label ownership.
+func removeOwnerReference(secret client.Object, obj sopsSecretObject) {
+	var ownerReferences []metav1.OwnerReference
+	for _, ownerReference := range secret.GetOwnerReferences() {
+		if ownerReference.UID != obj.GetUID() {
+			ownerReferences = append(ownerReferences, ownerReference)
+		}
+	}
+	secret.SetOwnerReferences(ownerReferences)
+}
+
+func adoptionPolicyOrDefault(policy secretsv1beta1.AdoptionPolicy) secretsv1beta1.AdoptionPolicy {
+	if policy == "" {
+		return secretsv1beta1.AdoptionPolicyNever
+	}
+	return policy
+}
+
+// canAdopt reports whether an existing object without the ownership label may be taken over under policy.
+func canAdopt(policy secretsv1beta1.AdoptionPolicy, empty bool) bool {
+	switch adoptionPolicyOrDefault(policy) {
+	case secretsv1beta1.AdoptionPolicyAlways:
+		return true
+	case secretsv1beta1.AdoptionPolicyIfEmpty:
+		return empty
+	default:
+		return false
+	}
+}
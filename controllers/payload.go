@@ -0,0 +1,346 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"go.mozilla.org/sops/v3"
+	"go.mozilla.org/sops/v3/stores/dotenv"
+	"go.mozilla.org/sops/v3/stores/ini"
+	"gopkg.in/yaml.v2"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// iniDefaultSection holds the keys of an ini file that precede the first section header.
+const iniDefaultSection = "DEFAULT"
+
+// validatePayloadSpec reports format and template settings that can never produce Secret data.
+func validatePayloadSpec(spec *secretsv1beta1.SopsSecretSpec) error {
+	if spec.Format == secretsv1beta1.FormatBinary && spec.FileKey == "" {
+		return errors.New("format binary requires fileKey")
+	}
+	_, err := parseDataTemplates(spec.Template.Data)
+	return err
+}
+
+// decryptFormat is the format sops reads and writes the data field in.
+func decryptFormat(spec *secretsv1beta1.SopsSecretSpec) string {
+	if spec.Format == "" {
+		return string(secretsv1beta1.FormatYAML)
+	}
+	return string(spec.Format)
+}
+
+// parsePayload turns the decrypted file into Secret data according to the format, flatten mode and null handling of spec.
+// Multiple documents are merged in order, later documents overriding keys of earlier ones.
+func parsePayload(plaintext []byte, spec *secretsv1beta1.SopsSecretSpec) (map[string]string, error) {
+	if spec.FileKey != "" {
+		data := map[string]string{spec.FileKey: string(plaintext)}
+		return data, decodeBinaryKeys(data, spec.BinaryKeys)
+	}
+
+	documents, err := loadDocuments(plaintext, spec.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string)
+	for _, root := range documents {
+		documentData := make(map[string]string)
+		for key, value := range root {
+			err = flattenValue(documentData, key, value, spec, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for key, value := range documentData {
+			data[key] = value
+		}
+	}
+	return data, decodeBinaryKeys(data, spec.BinaryKeys)
+}
+
+// yamlValue is a decoded yaml node that keeps the source text of a scalar next to its resolved value.
+type yamlValue struct {
+	// text is the scalar as written in the file, e.g. `01234` for the integer 668.
+	text string
+	// value is the resolved scalar, a map[string]yamlValue or a []yamlValue, nil for null.
+	value interface{}
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler. Nulls never reach it and leave the zero yamlValue.
+func (v *yamlValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var resolved interface{}
+	if err := unmarshal(&resolved); err != nil {
+		return err
+	}
+
+	switch resolved.(type) {
+	case nil:
+		return nil
+	case map[interface{}]interface{}:
+		// Keys are decoded into strings as well, which keeps `on` or `007` as written.
+		var tree map[string]yamlValue
+		if err := unmarshal(&tree); err != nil {
+			return err
+		}
+		v.value = tree
+		return nil
+	case []interface{}:
+		var list []yamlValue
+		if err := unmarshal(&list); err != nil {
+			return err
+		}
+		v.value = list
+		return nil
+	}
+	v.value = resolved
+	// A string target gets the scalar as written rather than its resolved value.
+	return unmarshal(&v.text)
+}
+
+// loadDocuments parses plaintext into one map per document.
+func loadDocuments(plaintext []byte, format secretsv1beta1.Format) ([]map[string]yamlValue, error) {
+	switch format {
+	case secretsv1beta1.FormatDotenv:
+		branches, err := (&dotenv.Store{}).LoadPlainFile(plaintext)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]yamlValue{treeBranchMap(branches[0])}, nil
+	case secretsv1beta1.FormatINI:
+		branches, err := (&ini.Store{}).LoadPlainFile(plaintext)
+		if err != nil {
+			return nil, err
+		}
+		root := treeBranchMap(branches[0])
+		// Keys outside any section are top-level keys, not a section named DEFAULT.
+		if defaults, ok := root[iniDefaultSection].value.(map[string]yamlValue); ok {
+			delete(root, iniDefaultSection)
+			for key, value := range defaults {
+				root[key] = value
+			}
+		}
+		return []map[string]yamlValue{root}, nil
+	case secretsv1beta1.FormatBinary:
+		return nil, errors.New("format binary requires fileKey")
+	}
+
+	// JSON is valid yaml, both are read by the yaml decoder.
+	var documents []map[string]yamlValue
+	decoder := yaml.NewDecoder(bytes.NewReader(plaintext))
+	for document := 0; ; document++ {
+		var value yamlValue
+		err := decoder.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Empty documents, e.g. a trailing separator.
+		if value.value == nil {
+			continue
+		}
+
+		root, ok := value.value.(map[string]yamlValue)
+		if !ok {
+			return nil, fmt.Errorf("document %d is a %T, not a map of keys to values", document, value.value)
+		}
+		documents = append(documents, root)
+	}
+}
+
+// treeBranchMap converts a sops tree branch to a map, dropping comments.
+func treeBranchMap(branch sops.TreeBranch) map[string]yamlValue {
+	root := make(map[string]yamlValue)
+	for _, item := range branch {
+		if _, ok := item.Key.(sops.Comment); ok {
+			continue
+		}
+		key := fmt.Sprint(item.Key)
+		if nested, ok := item.Value.(sops.TreeBranch); ok {
+			root[key] = yamlValue{value: treeBranchMap(nested)}
+			continue
+		}
+		root[key] = yamlValue{text: fmt.Sprint(item.Value), value: item.Value}
+	}
+	return root
+}
+
+// flattenValue stores value under key, descending into maps and lists as the flatten mode asks for.
+func flattenValue(data map[string]string, key string, value yamlValue, spec *secretsv1beta1.SopsSecretSpec, topLevel bool) error {
+	switch value.value.(type) {
+	case map[string]yamlValue, []yamlValue:
+		return flattenTree(data, key, value, spec, topLevel)
+	}
+
+	stringValue, ok, err := coerceScalar(value, spec)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", key, err)
+	}
+	if !ok {
+		return nil
+	}
+	// Within a document a key is unique, so a duplicate comes from flattening two paths onto the same key.
+	if _, exists := data[key]; exists {
+		return fmt.Errorf("flattened key %q is produced more than once", key)
+	}
+	data[key] = stringValue
+	return nil
+}
+
+func flattenTree(data map[string]string, key string, value yamlValue, spec *secretsv1beta1.SopsSecretSpec, topLevel bool) error {
+	separator := ""
+	switch spec.Flatten {
+	case secretsv1beta1.FlattenDotted:
+		separator = "."
+	case secretsv1beta1.FlattenUnderscore:
+		separator = "_"
+	case secretsv1beta1.FlattenJSON:
+		if !topLevel {
+			break
+		}
+		encoded, err := json.Marshal(jsonCompatible(value, spec.ScalarValues))
+		if err != nil {
+			return fmt.Errorf("key %q: %w", key, err)
+		}
+		data[key] = string(encoded)
+		return nil
+	}
+	if separator == "" {
+		return fmt.Errorf("key %q holds a %s, set spec.flatten to store nested values", key, kindOfTree(value))
+	}
+
+	switch tree := value.value.(type) {
+	case map[string]yamlValue:
+		for childKey, child := range tree {
+			err := flattenValue(data, key+separator+childKey, child, spec, false)
+			if err != nil {
+				return err
+			}
+		}
+	case []yamlValue:
+		for i, child := range tree {
+			err := flattenValue(data, key+separator+strconv.Itoa(i), child, spec, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// coerceScalar converts a yaml scalar to its Secret value, reporting false for nulls that are omitted.
+// Scalars are kept as written unless spec.scalarValues asks for them to be normalized:
+// integers in base 10, floats in their shortest exact form and booleans as true or false.
+func coerceScalar(value yamlValue, spec *secretsv1beta1.SopsSecretSpec) (string, bool, error) {
+	if value.value != nil && spec.ScalarValues != secretsv1beta1.ScalarValuesNormalize {
+		return value.text, true, nil
+	}
+
+	switch v := value.value.(type) {
+	case nil:
+		switch spec.NullValues {
+		case secretsv1beta1.NullValuesOmit:
+			return "", false, nil
+		case secretsv1beta1.NullValuesReject:
+			return "", false, errors.New("null values are rejected by spec.nullValues")
+		default:
+			return "", true, nil
+		}
+	case string:
+		return v, true, nil
+	case bool:
+		return strconv.FormatBool(v), true, nil
+	case int:
+		return strconv.Itoa(v), true, nil
+	case int64:
+		return strconv.FormatInt(v, 10), true, nil
+	case uint64:
+		return strconv.FormatUint(v, 10), true, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true, nil
+	default:
+		return "", false, fmt.Errorf("unsupported value of type %T", value.value)
+	}
+}
+
+// jsonCompatible converts a decoded tree into something encoding/json accepts.
+// Unless scalarValues is Normalize a number or boolean is only written as such when its text already is valid JSON,
+// anything else, e.g. `01234` or `yes`, becomes a JSON string holding the text as written.
+func jsonCompatible(value yamlValue, scalarValues secretsv1beta1.ScalarValues) interface{} {
+	switch v := value.value.(type) {
+	case map[string]yamlValue:
+		converted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			converted[key] = jsonCompatible(child, scalarValues)
+		}
+		return converted
+	case []yamlValue:
+		converted := make([]interface{}, len(v))
+		for i, child := range v {
+			converted[i] = jsonCompatible(child, scalarValues)
+		}
+		return converted
+	case nil, string:
+		return v
+	}
+	if scalarValues == secretsv1beta1.ScalarValuesNormalize {
+		return value.value
+	}
+	if json.Valid([]byte(value.text)) {
+		return json.RawMessage(value.text)
+	}
+	return value.text
+}
+
+func kindOfTree(value yamlValue) string {
+	if _, ok := value.value.([]yamlValue); ok {
+		return "list"
+	}
+	return "map"
+}
+
+// decodeBinaryKeys replaces the base64 values of keys matching binaryKeys with the bytes they encode.
+func decodeBinaryKeys(data map[string]string, binaryKeys []string) error {
+	patterns, err := compileKeyPatterns("binaryKeys", binaryKeys)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range data {
+		if !matchesAnyKeyPattern(patterns, key) {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("binary key %q is not valid base64: %w", key, err)
+		}
+		// Go strings hold arbitrary bytes, the value is copied into the Secret unchanged.
+		data[key] = string(decoded)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// listPolicies returns the SopsSecretPolicies that apply to obj.
+// Cluster scoped objects are not subject to policies, so nothing is fetched for them.
+func (r *SopsSecretReconciler) listPolicies(ctx context.Context, obj sopsSecretObject) ([]secretsv1beta1.SopsSecretPolicy, error) {
+	if obj.GetNamespace() == "" {
+		return nil, nil
+	}
+
+	policyList := &secretsv1beta1.SopsSecretPolicyList{}
+	err := r.List(ctx, policyList)
+	if err != nil {
+		return nil, err
+	}
+	return policyList.Items, nil
+}
+
+// targetAllowed reports whether obj may write its Secret into targetNamespace, with the reason when it may not.
+func (r *SopsSecretReconciler) targetAllowed(obj sopsSecretObject, policies []secretsv1beta1.SopsSecretPolicy, targetNamespace string) (bool, string) {
+	// Cluster scoped objects are owned by platform teams and may target any namespace.
+	if obj.GetNamespace() == "" || obj.GetNamespace() == targetNamespace {
+		return true, ""
+	}
+	if r.RestrictNamespacedTargets {
+		return false, "namespaced SopsSecrets may only target their own namespace, use a ClusterSopsSecret instead"
+	}
+
+	// Without any policy the controller keeps its original, unrestricted behaviour.
+	if len(policies) == 0 {
+		return true, ""
+	}
+	for _, policy := range policies {
+		for _, rule := range policy.Spec.Rules {
+			if matchesAnyPattern(rule.SourceNamespaces, obj.GetNamespace()) && matchesAnyPattern(rule.TargetNamespaces, targetNamespace) {
+				return true, ""
+			}
+		}
+	}
+	return false, fmt.Sprintf("no SopsSecretPolicy allows namespace %s to target namespace %s", obj.GetNamespace(), targetNamespace)
+}
+
+// matchesAnyPattern reports whether name matches any of the shell patterns.
+// Malformed patterns never match.
+func matchesAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
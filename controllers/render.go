@@ -0,0 +1,109 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are available to spec.template.data next to the text/template builtins.
+// None of them reach outside the template, a SopsSecret can't read files or the environment of the controller.
+var templateFuncs = template.FuncMap{
+	"b64enc": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"b64dec": func(s string) (string, error) {
+		decoded, err := base64.StdEncoding.DecodeString(s)
+		return string(decoded), err
+	},
+	"default": func(defaultValue, value string) string {
+		if value == "" {
+			return defaultValue
+		}
+		return value
+	},
+	"required": func(message, value string) (string, error) {
+		if value == "" {
+			return "", errors.New(message)
+		}
+		return value, nil
+	},
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"quote":   func(s string) string { return fmt.Sprintf("%q", s) },
+	"indent": func(spaces int, s string) string {
+		padding := strings.Repeat(" ", spaces)
+		return padding + strings.ReplaceAll(s, "\n", "\n"+padding)
+	},
+	"toJson": func(v interface{}) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+}
+
+// parseDataTemplates parses the templates of spec.template.data by the key they render to.
+func parseDataTemplates(templates map[string]string) (map[string]*template.Template, error) {
+	parsed := make(map[string]*template.Template, len(templates))
+	for key, text := range templates {
+		// Referencing a key that wasn't decrypted is an error rather than "<no value>" in the Secret.
+		tmpl, err := template.New(key).Option("missingkey=error").Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("template.data: %w", err)
+		}
+		parsed[key] = tmpl
+	}
+	return parsed, nil
+}
+
+// renderDataTemplates executes the templates against the decrypted data and adds the results to it.
+// Every template sees the decrypted data only, not the output of other templates.
+func renderDataTemplates(data map[string]string, templates map[string]string) error {
+	parsed, err := parseDataTemplates(templates)
+	if err != nil {
+		return err
+	}
+
+	// Render in a stable order so the same error is reported every time.
+	keys := make([]string, 0, len(parsed))
+	for key := range parsed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rendered := make(map[string]string, len(parsed))
+	for _, key := range keys {
+		out := &bytes.Buffer{}
+		err = parsed[key].Execute(out, data)
+		if err != nil {
+			return err
+		}
+		rendered[key] = out.String()
+	}
+	for key, value := range rendered {
+		data[key] = value
+	}
+	return nil
+}
@@ -23,15 +23,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
-	"reflect"
-	"strconv"
-	"strings"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
-	"gopkg.in/yaml.v2"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -48,11 +49,18 @@ import (
 
 const SecretChecksumAnotation string = "secrets.dhouti.dev/secretChecksum"
 const SopsChecksumAnnotation string = "secrets.dhouti.dev/sopsChecksum"
+const AdoptedChecksumAnnotation string = "secrets.dhouti.dev/adoptedChecksum"
+
+// ReconcileRequestedAtAnnotation forces a fresh decrypt and write of every target Secret whenever its value changes.
+const ReconcileRequestedAtAnnotation string = "secrets.dhouti.dev/reconcile-requested-at"
 
 const OwnershipLabel string = "secrets.dhouti.dev/owned-by-controller"
 
 const DeletionFinalizer string = "secrets.dhouti.dev/garbageCollection"
 
+// resyncJitterFactor is the maximum fraction added to the resync interval.
+const resyncJitterFactor = 0.1
+
 var _ Decryptor = &SopsDecrytor{}
 
 //go:generate moq -out mocks/decryptor_mock.go -pkg controllers_mocks . Decryptor
@@ -60,12 +68,48 @@ type Decryptor interface {
 	Decrypt([]byte, string) ([]byte, error)
 }
 
+// sopsSecretObject is implemented by both SopsSecret and ClusterSopsSecret.
+type sopsSecretObject interface {
+	client.Object
+	GetSpec() *secretsv1beta1.SopsSecretSpec
+	GetData() string
+	GetSecretType() corev1.SecretType
+	GetStatus() *secretsv1beta1.SopsSecretStatus
+}
+
+var _ sopsSecretObject = &secretsv1beta1.SopsSecret{}
+var _ sopsSecretObject = &secretsv1beta1.ClusterSopsSecret{}
+
 // SopsSecretReconciler reconciles a SopsSecret object
 type SopsSecretReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 	Decryptor
+
+	// PlaintextCache holds decrypted data between reconciles, disabled when nil.
+	PlaintextCache *PlaintextCache
+
+	// RestrictNamespacedTargets limits namespaced SopsSecrets to their own namespace.
+	RestrictNamespacedTargets bool
+
+	// ResyncInterval is how often objects are reconciled without a watch event, disabled when 0.
+	// It can be overridden per object with spec.refreshInterval.
+	ResyncInterval time.Duration
+
+	// DefaultDeletionPolicy applies to objects that don't set spec.deletionPolicy, Delete when empty.
+	DefaultDeletionPolicy secretsv1beta1.DeletionPolicy
+
+	// DefaultOwnershipMode applies to objects that don't set spec.ownershipMode, Label when empty.
+	DefaultOwnershipMode secretsv1beta1.OwnershipMode
+
+	// DecryptRetryBaseDelay and DecryptRetryMaxDelay bound the exponential backoff for transient decrypt failures.
+	DecryptRetryBaseDelay time.Duration
+	DecryptRetryMaxDelay  time.Duration
+
+	decryptBackoffOnce sync.Once
+	decryptBackoff     workqueue.RateLimiter
 }
 
 type SopsDecrytor struct {
@@ -81,7 +125,12 @@ func (r *SopsSecretReconciler) InjectDecryptor(d Decryptor) {
 
 // +kubebuilder:rbac:groups=secrets.dhouti.dev,resources=sopssecrets,verbs="*"
 // +kubebuilder:rbac:groups=secrets.dhouti.dev,resources=sopssecrets/status,verbs="*"
+// +kubebuilder:rbac:groups=secrets.dhouti.dev,resources=sopssecrets/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=secrets,verbs="*"
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs="*"
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=secrets.dhouti.dev,resources=sopssecretpolicies,verbs=get;list;watch
 
 func (r *SopsSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("sopssecret", req.NamespacedName)
@@ -101,48 +150,90 @@ func (r *SopsSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// If namespaces not set use namespace
-	if len(obj.Spec.Template.Namespaces) == 0 {
-		obj.Spec.Template.Namespaces = []string{
-			obj.Namespace,
-		}
+	return r.reconcileObject(ctx, log, obj)
+}
+
+// reconcileObject holds the reconcile logic shared by SopsSecret and ClusterSopsSecret.
+func (r *SopsSecretReconciler) reconcileObject(ctx context.Context, log logr.Logger, obj sopsSecretObject) (ctrl.Result, error) {
+	spec := obj.GetSpec()
+
+	targetName := obj.GetName()
+	if spec.Template.Name != "" {
+		targetName = spec.Template.Name
 	}
 
-	dt := obj.GetDeletionTimestamp()
+	// Object is being deleted, every Secret is handled before the finalizer is released.
+	// The namespace selector isn't resolved, an invalid one must not block the deletion and selected Secrets carry the ownership label.
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return r.finalize(ctx, log, obj, targetName, listedNamespaces(obj))
+	}
 
-	var finalizersDisabled bool
-	finalizersDisabledByEnv, _ := strconv.ParseBool(os.Getenv("DISABLE_FINALIZERS"))
-	if finalizersDisabledByEnv || obj.Spec.SkipFinalizers {
-		finalizersDisabled = true
+	// A suspended object is left exactly as it is, Secrets included.
+	if spec.Suspend {
+		log.Info("reconciliation is suspended")
+		return ctrl.Result{}, r.updateSuspendedStatus(ctx, obj)
 	}
 
+	// Cluster scoped objects have no namespace of their own to fall back to.
+	if obj.GetNamespace() == "" && len(spec.Template.Namespaces) == 0 && spec.Template.NamespaceSelector == nil {
+		return r.invalidSpec(ctx, log, obj, nil, errors.New("one of namespaces or namespaceSelector must be set"))
+	}
+
+	// Without a valid selector the targets are unknown, so nothing is garbage collected either.
+	if selector := spec.Template.NamespaceSelector; selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+			return r.invalidSpec(ctx, log, obj, listedNamespaces(obj), fmt.Errorf("invalid namespaceSelector: %w", err))
+		}
+	}
+
+	targetNamespaces, err := r.targetNamespaces(ctx, obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Nothing has to happen on deletion when every Secret is orphaned as is.
+	finalizersDisabled := !r.needsFinalizer(obj, targetNamespaces)
+
 	// Cleanup secrets in namespaces no longer in spec.
-	ownershipLabelValue := fmt.Sprintf("%s.%s", obj.Name, obj.Namespace)
-	secretList := &corev1.SecretList{}
-	err = r.List(ctx, secretList, client.MatchingLabels{
-		OwnershipLabel: ownershipLabelValue,
-	})
+	ownedSecrets, err := r.listOwnedSecrets(ctx, obj)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	for _, secretListItem := range secretList.Items {
+	for i := range ownedSecrets {
+		secretListItem := &ownedSecrets[i]
 		var foundItem bool
-		for _, curNamespace := range obj.Spec.Template.Namespaces {
+		for _, curNamespace := range targetNamespaces {
 			if secretListItem.ObjectMeta.Namespace == curNamespace {
 				foundItem = true
 			}
 		}
 		if !foundItem {
-			err = r.Delete(ctx, &secretListItem)
-			if err != nil && !k8serrors.IsNotFound(err) {
+			err = r.collectUntargeted(ctx, obj, secretListItem)
+			if err != nil {
 				return ctrl.Result{}, err
 			}
 		}
 	}
 
-	// Add finalizer if not set and not currently being deleted
-	if dt.IsZero() && !controllerutil.ContainsFinalizer(obj, DeletionFinalizer) && !finalizersDisabled {
+	// ConfigMaps also go away when no keys are routed to them anymore or the target name changed.
+	ownedConfigMaps, err := r.listOwnedConfigMaps(ctx, obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range ownedConfigMaps {
+		configMap := &ownedConfigMaps[i]
+		if !isStaleConfigMap(obj, configMap, targetName, targetNamespaces) {
+			continue
+		}
+		err = r.collectUntargeted(ctx, obj, configMap)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Add finalizer if not set
+	if !controllerutil.ContainsFinalizer(obj, DeletionFinalizer) && !finalizersDisabled {
 		controllerutil.AddFinalizer(obj, DeletionFinalizer)
 		err = r.Update(ctx, obj)
 		if err != nil {
@@ -161,203 +252,461 @@ func (r *SopsSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	targetName := obj.Name
-	if obj.Spec.Template.Name != "" {
-		targetName = obj.Spec.Template.Name
+	// An invalid spec is reported for every target without writing anything, until the spec changes.
+	keys, err := newKeyMatcher(obj)
+	if err == nil {
+		err = validatePayloadSpec(spec)
+	}
+	if err == nil {
+		err = validateGenerator(obj)
 	}
+	if err != nil {
+		return r.invalidSpec(ctx, log, obj, targetNamespaces, err)
+	}
+
+	policies, err := r.listPolicies(ctx, obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Decrypt at most once no matter how many namespaces are targeted.
+	data := r.newDecryptedData(log, obj)
 
 	var requeue bool
-	for _, targetNamespace := range obj.Spec.Template.Namespaces {
+	namespaceStatuses := make([]secretsv1beta1.SopsSecretNamespaceStatus, 0, len(targetNamespaces))
+	for _, targetNamespace := range targetNamespaces {
 		secretDestination := types.NamespacedName{
 			Name:      targetName,
 			Namespace: targetNamespace,
 		}
-		res, err := r.ReconcileNamespace(ctx, log, finalizersDisabled, obj, secretDestination)
+
+		if allowed, message := r.targetAllowed(obj, policies, targetNamespace); !allowed {
+			namespaceStatuses = append(namespaceStatuses, secretsv1beta1.SopsSecretNamespaceStatus{
+				Namespace: targetNamespace,
+				State:     secretsv1beta1.SyncStateDenied,
+				Reason:    secretsv1beta1.ReasonTargetDenied,
+				Message:   message,
+			})
+			r.event(obj, corev1.EventTypeWarning, secretsv1beta1.ReasonTargetDenied, "Skipped secret %s: %s", secretDestination, message)
+			continue
+		}
+
+		res, namespaceStatus, err := r.ReconcileNamespace(ctx, log, obj, data, keys, secretDestination)
+		namespaceStatuses = append(namespaceStatuses, namespaceStatus)
 		if res.Requeue {
 			requeue = true
 		}
 
-		// If there's an error return immediately
+		// If there's an error record it and return immediately
 		if err != nil {
+			if statusErr := r.updateStatus(ctx, obj, namespaceStatuses); statusErr != nil {
+				log.Error(statusErr, "failed to update status")
+			}
+			var decryptErr *decryptError
+			if errors.As(err, &decryptErr) {
+				return r.decryptFailureResult(log, obj, decryptErr), nil
+			}
 			return res, err
 		}
 	}
+	r.getDecryptBackoff().Forget(obj.GetUID())
+
+	err = r.updateHandledStatus(ctx, obj, namespaceStatuses)
+	if err != nil || requeue {
+		return ctrl.Result{Requeue: requeue}, err
+	}
+
+	// Check again later even without watch events, this catches drift the watches missed.
+	return ctrl.Result{RequeueAfter: r.resyncInterval(obj)}, nil
+}
 
-	return ctrl.Result{Requeue: requeue}, err
+// invalidSpec reports err for every target without writing anything, until the spec changes.
+func (r *SopsSecretReconciler) invalidSpec(ctx context.Context, log logr.Logger, obj sopsSecretObject, targetNamespaces []string, err error) (ctrl.Result, error) {
+	log.Error(err, "invalid spec")
+	r.event(obj, corev1.EventTypeWarning, secretsv1beta1.ReasonInvalidSpec, "Invalid spec: %v", err)
+	return ctrl.Result{}, r.updateInvalidSpecStatus(ctx, obj, targetNamespaces, err)
 }
 
-func (r *SopsSecretReconciler) ReconcileNamespace(ctx context.Context, log logr.Logger, finalizersDisabled bool, obj *secretsv1beta1.SopsSecret, secretDestination types.NamespacedName) (ctrl.Result, error) {
+func (r *SopsSecretReconciler) ReconcileNamespace(ctx context.Context, log logr.Logger, obj sopsSecretObject, data *decryptedData, keys *keyMatcher, secretDestination types.NamespacedName) (ctrl.Result, secretsv1beta1.SopsSecretNamespaceStatus, error) {
+	namespaceStatus := secretsv1beta1.SopsSecretNamespaceStatus{
+		Namespace: secretDestination.Namespace,
+		State:     secretsv1beta1.SyncStateSynced,
+		Reason:    secretsv1beta1.ReasonSucceeded,
+	}
+	if previous := findNamespaceStatus(obj.GetStatus().Namespaces, secretDestination.Namespace); previous != nil {
+		namespaceStatus.LastSyncedTime = previous.LastSyncedTime
+	}
+
 	// Fetch the secret
 	// If ownership label not present on existing secret short circuit
 	fetchSecret := &corev1.Secret{}
 	err := r.Get(ctx, secretDestination, fetchSecret)
 	secretNotFound := k8serrors.IsNotFound(err)
 	if err != nil && !secretNotFound {
-		return ctrl.Result{}, err
+		return ctrl.Result{}, failedNamespaceStatus(namespaceStatus, secretsv1beta1.ReasonApplyFailed, err), err
 	}
+	var adopted bool
 	if !secretNotFound {
 		_, ok := fetchSecret.Labels[OwnershipLabel]
 		if !ok {
-			// The secret does not have the ownership label, exit
-			return ctrl.Result{}, nil
+			adoptionPolicy := obj.GetSpec().AdoptionPolicy
+			if !canAdopt(adoptionPolicy, len(fetchSecret.Data) == 0) {
+				// The secret does not have the ownership label and may not be adopted, exit
+				namespaceStatus.State = secretsv1beta1.SyncStateSkipped
+				namespaceStatus.Reason = secretsv1beta1.ReasonNotOwned
+				namespaceStatus.Message = fmt.Sprintf("secret %s exists without the %s label, adoption refused by adoptionPolicy %q", secretDestination, OwnershipLabel, adoptionPolicyOrDefault(adoptionPolicy))
+				skippedUnowned.WithLabelValues(secretDestination.Namespace, namespaceStatus.Reason).Inc()
+				r.event(obj, corev1.EventTypeWarning, secretsv1beta1.ReasonNotOwned, "Skipped secret %s: %s label not set", secretDestination, OwnershipLabel)
+				return ctrl.Result{}, namespaceStatus, nil
+			}
+			adopted = true
+		} else if !isOwnedBy(fetchSecret, obj) {
+			// The secret is managed from another SopsSecret, don't fight over it.
+			namespaceStatus.State = secretsv1beta1.SyncStateSkipped
+			namespaceStatus.Reason = secretsv1beta1.ReasonOwnedByOther
+			namespaceStatus.Message = fmt.Sprintf("secret %s is owned by another object", secretDestination)
+			skippedUnowned.WithLabelValues(secretDestination.Namespace, namespaceStatus.Reason).Inc()
+			r.event(obj, corev1.EventTypeWarning, secretsv1beta1.ReasonOwnedByOther, "Skipped secret %s: owned by another object", secretDestination)
+			return ctrl.Result{}, namespaceStatus, nil
 		}
 	}
 
-	dt := obj.GetDeletionTimestamp()
-	// Object is being deleted
-	if !dt.IsZero() {
-		if controllerutil.ContainsFinalizer(obj, DeletionFinalizer) {
-			// Delete the secret if it exists and finalizers enabled
-			if !secretNotFound && !finalizersDisabled {
-				err = r.Delete(ctx, fetchSecret)
-				if err != nil {
-					return ctrl.Result{Requeue: true}, err
-				}
-			}
-
-			// Remove the finalizer and exit
-			controllerutil.RemoveFinalizer(obj, DeletionFinalizer)
-			err = r.Update(ctx, obj)
-			if err != nil {
-				return ctrl.Result{Requeue: true}, errors.New("unable to remove finalizer")
-			}
+	// Keys routed to a ConfigMap are written next to the Secret under the same name, ownership works the same way.
+	var fetchConfigMap *corev1.ConfigMap
+	if keys.routesConfigMap {
+		fetchConfigMap, err = r.getConfigMap(ctx, secretDestination)
+		if err != nil {
+			return ctrl.Result{}, failedNamespaceStatus(namespaceStatus, secretsv1beta1.ReasonApplyFailed, err), err
+		}
+		if reason := configMapConflict(obj, fetchConfigMap); reason != "" {
+			namespaceStatus.State = secretsv1beta1.SyncStateSkipped
+			namespaceStatus.Reason = reason
+			namespaceStatus.Message = fmt.Sprintf("configmap %s exists and is not owned by this object", secretDestination)
+			skippedUnowned.WithLabelValues(secretDestination.Namespace, namespaceStatus.Reason).Inc()
+			r.event(obj, corev1.EventTypeWarning, reason, "Skipped configmap %s: not owned by this object", secretDestination)
+			return ctrl.Result{}, namespaceStatus, nil
 		}
 	}
 
 	// Calculate hashes of both objects to see if they are in desired state.
-	secretDataBytes, err := json.Marshal(fetchSecret.Data)
+	// Only managed keys count, other writers changing ignored keys is not drift.
+	secretDataBytes, err := json.Marshal(keys.ManagedData(fetchSecret.Data))
 	if err != nil {
-		return ctrl.Result{}, err
+		return ctrl.Result{}, failedNamespaceStatus(namespaceStatus, secretsv1beta1.ReasonApplyFailed, err), err
 	}
 
 	currentSecretChecksum := hashItem(secretDataBytes)
-	currentSopsChecksum := hashItem([]byte(obj.Data))
+	currentSopsChecksum := payloadChecksum(obj)
+
+	spec := obj.GetSpec()
 
-	// Handle annotations from template
+	// Handle annotations from template, copied so the spec of obj is never modified
 	secretAnnotations := make(map[string]string)
-	if obj.Spec.Template.Annotations != nil {
-		secretAnnotations = obj.Spec.Template.Annotations
+	for k, v := range spec.Template.Annotations {
+		secretAnnotations[k] = v
 	}
 	secretAnnotations[SecretChecksumAnotation] = currentSecretChecksum
 	secretAnnotations[SopsChecksumAnnotation] = currentSopsChecksum
+	for k, v := range ownerAnnotations(obj) {
+		secretAnnotations[k] = v
+	}
+	// Remember what an adopted secret contained before it was taken over.
+	if adopted {
+		secretAnnotations[AdoptedChecksumAnnotation] = currentSecretChecksum
+	} else if adoptedChecksum, ok := fetchSecret.Annotations[AdoptedChecksumAnnotation]; ok {
+		secretAnnotations[AdoptedChecksumAnnotation] = adoptedChecksum
+	}
 
 	// Handle labels from template
 	secretLabels := make(map[string]string)
-	if obj.Spec.Template.Labels != nil {
-		secretLabels = obj.Spec.Template.Labels
+	for k, v := range spec.Template.Labels {
+		secretLabels[k] = v
 	}
 
-	ownershipLabelValue := fmt.Sprintf("%s.%s", obj.Name, obj.Namespace)
-	secretLabels[OwnershipLabel] = string(ownershipLabelValue)
+	secretLabels[OwnershipLabel] = ownershipLabelValue(obj)
 
+	// Owner references are added or removed in place, which also migrates Secrets between ownership modes.
+	ownerReference := r.wantsOwnerReference(obj, secretDestination.Namespace, r.deletionPolicy(obj, secretDestination.Namespace))
+
+	// Other tools may add their own labels and annotations, only ours have to match
+	// and the controller must not still be managing any it no longer sets.
 	existingSecretChecksum, hasSecretChecksum := fetchSecret.Annotations[SecretChecksumAnotation]
 	existingSopsChecksum, hasSopsChecksum := fetchSecret.Annotations[SopsChecksumAnnotation]
 	if hasSecretChecksum && hasSopsChecksum &&
 		existingSecretChecksum == currentSecretChecksum &&
 		existingSopsChecksum == currentSopsChecksum &&
-		reflect.DeepEqual(fetchSecret.Annotations, secretAnnotations) &&
-		reflect.DeepEqual(fetchSecret.Labels, secretLabels) {
+		appliedExactly(fetchSecret, "f:annotations", fetchSecret.Annotations, secretAnnotations) &&
+		appliedExactly(fetchSecret, "f:labels", fetchSecret.Labels, secretLabels) &&
+		hasOwnerReference(fetchSecret, obj) == ownerReference &&
+		(!keys.routesConfigMap || configMapInSync(fetchConfigMap, currentSopsChecksum)) &&
+		!reconcileRequested(obj) {
 		// That's one big if
 		log.Info("Objects matched, skipping.")
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, namespaceStatus, nil
 	}
 
-	// Decrypt the Data field using Sops
-	unencryptedData, err := r.Decrypt([]byte(obj.Data), "yaml")
-	if err != nil {
-		log.Error(err, "failed to decrypt data")
-		return ctrl.Result{}, err
-	}
+	// The data was changed out from under us if the source is unchanged but the secret's data is not what was last written.
+	drifted := hasSecretChecksum && hasSopsChecksum &&
+		existingSopsChecksum == currentSopsChecksum &&
+		existingSecretChecksum != currentSecretChecksum
 
-	// Convert decryted secret into map[string]string, sadly cannot unmarshal directly into []byte
-	secretDataStrings := make(map[string]string)
-	err = yaml.Unmarshal(unencryptedData, &secretDataStrings)
+	decryptedDataStrings, err := data.Get()
 	if err != nil {
-		log.Error(err, "failed to unmarshal decrypted data")
-		return ctrl.Result{}, err
+		reason := secretsv1beta1.ReasonDecryptionFailed
+		var decryptErr *decryptError
+		if errors.As(err, &decryptErr) {
+			reason = decryptErr.Reason
+		}
+		return ctrl.Result{}, failedNamespaceStatus(namespaceStatus, reason, err), err
 	}
+	secretDataStrings, configMapData := splitConfigMapData(keys, decryptedDataStrings)
 
-	// Convert map[string]string to map[string][]byte for compatibility with corev1.Secret
+	// Convert map[string]string to map[string][]byte for compatibility with corev1.Secret.
+	// Ignored keys are left to other writers and never applied, the decrypted value only fills in a missing key.
 	generatedSecretData := make(map[string][]byte)
+	ignoredSecretData := make(map[string][]byte)
 	for k, v := range secretDataStrings {
+		// Keys outside managedKeys are never written, not even when the live Secret lacks them.
+		if keys.Unmanaged(k) {
+			continue
+		}
+		if keys.Ignored(k) {
+			ignoredSecretData[k] = []byte(v)
+			continue
+		}
 		generatedSecretData[k] = []byte(v)
 	}
-
-	// Add back ignored keys from live secret
-	ignoredKeys := obj.Spec.IgnoredKeys
-	if len(ignoredKeys) > 0 {
-		for _, key := range ignoredKeys {
-			existingKey, ok := fetchSecret.Data[key]
-			if !ok {
-				continue
-			}
-
-			generatedSecretData[key] = existingKey
+	for key, existingKey := range fetchSecret.Data {
+		if keys.Ignored(key) {
+			ignoredSecretData[key] = existingKey
 		}
 	}
 
+	// The API server would reject the Secret with a generic error, report exactly why without writing it.
+	// Retrying can't help until the object changes.
+	resultingSecretData := make(map[string][]byte, len(generatedSecretData)+len(ignoredSecretData))
+	for k, v := range ignoredSecretData {
+		resultingSecretData[k] = v
+	}
+	for k, v := range generatedSecretData {
+		resultingSecretData[k] = v
+	}
+	err = validateSecret(secretType(obj), secretAnnotations, resultingSecretData)
+	if err == nil && keys.routesConfigMap {
+		err = validateConfigMap(configMapData)
+	}
+	if err != nil {
+		r.event(obj, corev1.EventTypeWarning, secretsv1beta1.ReasonInvalidSecret, "Secret %s is invalid: %v", secretDestination, err)
+		err = &decryptError{Reason: secretsv1beta1.ReasonInvalidSecret, Err: err}
+		return ctrl.Result{}, failedNamespaceStatus(namespaceStatus, secretsv1beta1.ReasonInvalidSecret, err), err
+	}
+
 	// Prevents an unnecessary reconcile on new objects
-	secretDataBytes, err = json.Marshal(generatedSecretData)
+	secretDataBytes, err = json.Marshal(keys.ManagedData(generatedSecretData))
 	if err != nil {
-		return ctrl.Result{}, err
+		return ctrl.Result{}, failedNamespaceStatus(namespaceStatus, secretsv1beta1.ReasonApplyFailed, err), err
 	}
 	currentSecretChecksum = hashItem(secretDataBytes)
 	secretAnnotations[SecretChecksumAnotation] = currentSecretChecksum
 
+	// Server-side apply only manages the fields set here, labels and annotations added by other tools are kept.
 	generatedSecret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretDestination.Name,
-			Namespace: secretDestination.Namespace,
+			Name:        secretDestination.Name,
+			Namespace:   secretDestination.Namespace,
+			Annotations: secretAnnotations,
+			Labels:      secretLabels,
 		},
+		Type: secretType(obj),
+		Data: generatedSecretData,
+	}
+	if ownerReference {
+		err = controllerutil.SetControllerReference(obj, generatedSecret, r.Scheme)
+	}
+	if err == nil {
+		err = r.Patch(ctx, generatedSecret, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+	}
+	if err == nil {
+		err = r.pruneSecret(ctx, generatedSecret, obj, generatedSecretData, ignoredSecretData, keys, ownerReference)
+	}
+	if err == nil && keys.routesConfigMap {
+		err = r.applyConfigMap(ctx, obj, secretDestination, configMapData, secretLabels, currentSopsChecksum, ownerReference)
+	}
+
+	if err != nil {
+		log.Error(err, "failed to apply changes to secret")
+		r.event(obj, corev1.EventTypeWarning, secretsv1beta1.ReasonApplyFailed, "Failed to apply secret %s: %v", secretDestination, err)
+		return ctrl.Result{}, failedNamespaceStatus(namespaceStatus, secretsv1beta1.ReasonApplyFailed, err), err
+	}
+
+	if adopted {
+		log.Info("adopted existing secret", "secret", secretDestination)
+		r.event(obj, corev1.EventTypeNormal, EventReasonAdopted, "Adopted existing secret %s", secretDestination)
+	}
+
+	if drifted {
+		driftRestorations.WithLabelValues(secretDestination.Namespace).Inc()
+		r.event(obj, corev1.EventTypeNormal, EventReasonDriftRestored, "Restored modified data in secret %s", secretDestination)
+		r.event(generatedSecret, corev1.EventTypeNormal, EventReasonDriftRestored, "Restored data managed by %s %s", kindOf(obj), client.ObjectKeyFromObject(obj))
+	} else {
+		r.event(obj, corev1.EventTypeNormal, EventReasonSynced, "Synced secret %s", secretDestination)
+	}
+
+	now := metav1.Now()
+	namespaceStatus.LastSyncedTime = &now
+	return ctrl.Result{}, namespaceStatus, nil
+
+}
+
+// resyncInterval returns the jittered delay until obj is reconciled again, or 0 when periodic resync is disabled.
+func (r *SopsSecretReconciler) resyncInterval(obj sopsSecretObject) time.Duration {
+	interval := r.ResyncInterval
+	if refreshInterval := obj.GetSpec().RefreshInterval; refreshInterval != nil {
+		interval = refreshInterval.Duration
+	}
+	if interval <= 0 {
+		return 0
 	}
+	// Spread the requeues so objects created together don't all resync at once.
+	return wait.Jitter(interval, resyncJitterFactor)
+}
 
-	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, generatedSecret, func() error {
-		generatedSecret.Annotations = secretAnnotations
-		generatedSecret.Labels = secretLabels
-		generatedSecret.Type = obj.Type
+// targetNamespaces returns the namespaces listed in the template plus those matched by its namespace selector.
+func (r *SopsSecretReconciler) targetNamespaces(ctx context.Context, obj sopsSecretObject) ([]string, error) {
+	template := obj.GetSpec().Template
+	targetNamespaces := listedNamespaces(obj)
+	if template.NamespaceSelector == nil {
+		return targetNamespaces, nil
+	}
 
-		generatedSecret.Data = generatedSecretData
-		return nil
-	})
+	selector, err := metav1.LabelSelectorAsSelector(template.NamespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespaceSelector: %w", err)
+	}
 
+	namespaceList := &corev1.NamespaceList{}
+	err = r.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: selector})
 	if err != nil {
-		log.Error(err, "failed to apply changes to secret")
+		return nil, err
+	}
+
+	selectedNamespaces := make([]string, 0, len(namespaceList.Items))
+	for _, namespace := range namespaceList.Items {
+		// Secrets can't be created in a terminating namespace.
+		if !namespace.GetDeletionTimestamp().IsZero() || containsString(targetNamespaces, namespace.Name) {
+			continue
+		}
+		selectedNamespaces = append(selectedNamespaces, namespace.Name)
 	}
+	sort.Strings(selectedNamespaces)
 
-	return ctrl.Result{}, err
+	return append(targetNamespaces, selectedNamespaces...), nil
+}
 
+// listedNamespaces returns the target namespaces of obj that don't depend on the namespace selector.
+func listedNamespaces(obj sopsSecretObject) []string {
+	template := obj.GetSpec().Template
+
+	// If neither namespaces nor a selector are set use namespace, cluster scoped objects have none to fall back to.
+	if len(template.Namespaces) == 0 && template.NamespaceSelector == nil {
+		if obj.GetNamespace() == "" {
+			return nil
+		}
+		return []string{obj.GetNamespace()}
+	}
+	return append([]string{}, template.Namespaces...)
 }
 
 func (r *SopsSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := registerManagedSecretsCollector(mgr.GetClient())
+	if err != nil {
+		return err
+	}
+
+	enqueueOwner := handler.EnqueueRequestsFromMapFunc(
+		func(o client.Object) []reconcile.Request {
+			kind, owner, ok := ownerOf(o)
+			if !ok || kind != sopsSecretKind {
+				return nil
+			}
+
+			return []reconcile.Request{
+				{
+					NamespacedName: owner,
+				},
+			}
+		},
+	)
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&secretsv1beta1.SopsSecret{}).
 		// Use a WatchMap over an Ownerref, this should allow for safe deletion of the CRD and all objects without garbage collecting all of the secrets.
 		// Would require scaling down the controller first.
-		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(
+		Watches(&source.Kind{Type: &corev1.Secret{}}, enqueueOwner).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, enqueueOwner).
+		// Namespaces coming and going can change the targets of any SopsSecret using a selector.
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(
 			func(o client.Object) []reconcile.Request {
-				ownershipLabel, ok := o.GetLabels()[OwnershipLabel]
-				if !ok {
+				sopsSecretList := &secretsv1beta1.SopsSecretList{}
+				err := r.List(context.Background(), sopsSecretList)
+				if err != nil {
+					r.Log.Error(err, "unable to list SopsSecrets for namespace event", "namespace", o.GetName())
 					return nil
 				}
 
-				splitOwnershipLabel := strings.Split(ownershipLabel, ".")
-				if len(splitOwnershipLabel) != 2 {
+				var requests []reconcile.Request
+				for _, sopsSecret := range sopsSecretList.Items {
+					if sopsSecret.Spec.Template.NamespaceSelector == nil {
+						continue
+					}
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{
+							Name:      sopsSecret.Name,
+							Namespace: sopsSecret.Namespace,
+						},
+					})
+				}
+				return requests
+			},
+		)).
+		// Policy changes can allow or deny targets of any SopsSecret.
+		Watches(&source.Kind{Type: &secretsv1beta1.SopsSecretPolicy{}}, handler.EnqueueRequestsFromMapFunc(
+			func(o client.Object) []reconcile.Request {
+				sopsSecretList := &secretsv1beta1.SopsSecretList{}
+				err := r.List(context.Background(), sopsSecretList)
+				if err != nil {
+					r.Log.Error(err, "unable to list SopsSecrets for policy event", "policy", o.GetName())
 					return nil
 				}
 
-				return []reconcile.Request{
-					{
+				requests := make([]reconcile.Request, 0, len(sopsSecretList.Items))
+				for _, sopsSecret := range sopsSecretList.Items {
+					requests = append(requests, reconcile.Request{
 						NamespacedName: types.NamespacedName{
-							Name:      splitOwnershipLabel[0],
-							Namespace: splitOwnershipLabel[1],
+							Name:      sopsSecret.Name,
+							Namespace: sopsSecret.Namespace,
 						},
-					},
+					})
 				}
+				return requests
 			},
 		)).
 		Complete(r)
 }
 
+func containsString(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
 func hashItem(data []byte) string {
 	hash := sha1.Sum(data)
 	encodedHash := hex.EncodeToString(hash[:])
@@ -20,18 +20,24 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	sopssecretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+	"github.com/dhouti/sops-converter/controllers"
 	controllersmocks "github.com/dhouti/sops-converter/controllers/mocks"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var currentNamespace string
@@ -89,6 +95,168 @@ var _ = Describe("sopssecret controller", func() {
 				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
 			}, maxTimeout).Should(HaveOccurred())
 		})
+
+		It("reports the failure in status", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "this isn't yaml either"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() string {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				condition := meta.FindStatusCondition(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.DecryptedCondition)
+				if condition == nil || condition.Status != metav1.ConditionFalse {
+					return ""
+				}
+				return condition.Reason
+			}, maxTimeout).Should(Equal(sopssecretsv1beta1.ReasonUnmarshalFailed))
+
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			err = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(meta.IsStatusConditionFalse(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.ReadyCondition)).To(BeTrue())
+			Expect(fetchSopsSecret.Status.Namespaces).To(HaveLen(1))
+			Expect(fetchSopsSecret.Status.Namespaces[0].State).To(Equal(sopssecretsv1beta1.SyncStateFailed))
+		})
+	})
+
+	Context("classifies decryption failures", func() {
+		It("stops retrying a permanent failure", func() {
+			mockedDecrytor.DecryptFunc = func(input []byte, format string) ([]byte, error) {
+				return nil, fmt.Errorf("Failed to verify data integrity. expected mac %q, got %q", "a", "b")
+			}
+			newSecret := getTestSopsSecret()
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() string {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				condition := meta.FindStatusCondition(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.DecryptedCondition)
+				if condition == nil {
+					return ""
+				}
+				return condition.Reason
+			}, maxTimeout).Should(Equal(sopssecretsv1beta1.ReasonMACMismatch))
+
+			res, err := usedReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: getNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Requeue).To(BeFalse())
+			Expect(res.RequeueAfter).To(BeZero())
+
+			decryptCalls := len(mockedDecrytor.DecryptCalls())
+			Consistently(func() int {
+				return len(mockedDecrytor.DecryptCalls())
+			}, 2).Should(Equal(decryptCalls))
+		})
+
+		It("backs off on a transient failure", func() {
+			mockedDecrytor.DecryptFunc = func(input []byte, format string) ([]byte, error) {
+				return nil, fmt.Errorf("Error decrypting key: RequestError: send request failed: dial tcp: i/o timeout")
+			}
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: transient"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() string {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				condition := meta.FindStatusCondition(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.DecryptedCondition)
+				if condition == nil {
+					return ""
+				}
+				return condition.Reason
+			}, maxTimeout).Should(Equal(sopssecretsv1beta1.ReasonProviderUnavailable))
+
+			first, err := usedReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: getNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first.RequeueAfter).To(BeNumerically(">", 0))
+			second, err := usedReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: getNamespacedName()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second.RequeueAfter).To(BeNumerically(">", first.RequeueAfter))
+		})
+
+		It("keeps a requested reconcile pending until it succeeds", func() {
+			failing := int32(1)
+			mockedDecrytor.DecryptFunc = func(input []byte, format string) ([]byte, error) {
+				if atomic.LoadInt32(&failing) == 1 {
+					return nil, fmt.Errorf("Error decrypting key: RequestError: send request failed: dial tcp: i/o timeout")
+				}
+				return input, nil
+			}
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: requested"
+			newSecret.Annotations[controllers.ReconcileRequestedAtAnnotation] = "now"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			Eventually(func() bool {
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				return meta.IsStatusConditionFalse(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.DecryptedCondition)
+			}, maxTimeout).Should(BeTrue())
+			Expect(fetchSopsSecret.Status.LastHandledReconcileAt).To(BeEmpty())
+
+			atomic.StoreInt32(&failing, 0)
+			Eventually(func() string {
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				return fetchSopsSecret.Status.LastHandledReconcileAt
+			}, maxTimeout).Should(Equal("now"))
+		})
+	})
+
+	Context("orphaned secrets", func() {
+		It("deletes secrets whose owner no longer exists", func() {
+			orphanedSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      currentObjectName,
+					Namespace: currentNamespace,
+					Labels: map[string]string{
+						controllers.OwnershipLabel: "orphaned",
+					},
+					Annotations: map[string]string{
+						controllers.OwnerKindAnnotation:      "SopsSecret",
+						controllers.OwnerNameAnnotation:      currentObjectName,
+						controllers.OwnerNamespaceAnnotation: currentNamespace,
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, orphanedSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			collector := &controllers.OrphanCollector{
+				Client:    k8sClient,
+				APIReader: k8sClient,
+				Log:       ctrl.Log.WithName("orphans"),
+				Policy:    controllers.OrphanPolicyDelete,
+				DryRun:    true,
+			}
+			Eventually(func() []corev1.Secret {
+				secretList := &corev1.SecretList{}
+				_ = k8sClient.List(ctx, secretList, client.InNamespace(currentNamespace), client.HasLabels{controllers.OwnershipLabel})
+				return secretList.Items
+			}, maxTimeout).Should(HaveLen(1))
+
+			err = collector.Sweep(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), &corev1.Secret{})
+			}, 1).ShouldNot(HaveOccurred())
+
+			collector.DryRun = false
+			err = collector.Sweep(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, getNamespacedName(), &corev1.Secret{})
+				return k8serrors.IsNotFound(err)
+			}, maxTimeout).Should(BeTrue())
+		})
 	})
 
 	Context("decrypts secrets successfuly", func() {
@@ -110,9 +278,355 @@ var _ = Describe("sopssecret controller", func() {
 
 			Expect(createdSecret.Data["test"]).To(Equal([]byte("value")))
 		})
+
+		It("reports readiness in status", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "status: ready"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			Eventually(func() bool {
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				return meta.IsStatusConditionTrue(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.ReadyCondition)
+			}, maxTimeout).Should(BeTrue())
+
+			Expect(fetchSopsSecret.Status.ObservedGeneration).To(Equal(fetchSopsSecret.Generation))
+			Expect(fetchSopsSecret.Status.LastSyncedTime).ToNot(BeNil())
+			Expect(fetchSopsSecret.Status.Namespaces).To(HaveLen(1))
+			Expect(fetchSopsSecret.Status.Namespaces[0].Namespace).To(Equal(currentNamespace))
+			Expect(fetchSopsSecret.Status.Namespaces[0].State).To(Equal(sopssecretsv1beta1.SyncStateSynced))
+		})
+
+		It("records an event when the secret is synced", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "event: synced"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() []string {
+				return getEventReasons(newSecret.Name)
+			}, maxTimeout).Should(ContainElement(controllers.EventReasonSynced))
+		})
 	})
 
 	Context("General behaviors", func() {
+		It("keeps scalars and keys as written", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Flatten = sopssecretsv1beta1.FlattenJSON
+			newSecret.Data = `zip: 01234
+version: 1.10
+enabled: yes
+debug: on
+on: x
+007: y
+db:
+  zip: 01234
+  version: 1.10
+  enabled: yes
+  tls: true
+  on: off
+`
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Expect(createdSecret.Data).To(Equal(map[string][]byte{
+				"zip":     []byte("01234"),
+				"version": []byte("1.10"),
+				"enabled": []byte("yes"),
+				"debug":   []byte("on"),
+				"on":      []byte("x"),
+				"007":     []byte("y"),
+				"db":      []byte(`{"enabled":"yes","on":"off","tls":true,"version":1.10,"zip":"01234"}`),
+			}))
+		})
+
+		It("normalizes scalars when asked to", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.ScalarValues = sopssecretsv1beta1.ScalarValuesNormalize
+			newSecret.Data = `zip: 01234
+version: 1.10
+enabled: yes
+007: on
+`
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Expect(createdSecret.Data).To(HaveKeyWithValue("zip", []byte("668")))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("version", []byte("1.1")))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("enabled", []byte("true")))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("007", []byte("true")))
+		})
+
+		It("flattens nested values and coerces scalars", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Flatten = sopssecretsv1beta1.FlattenDotted
+			newSecret.Spec.NullValues = sopssecretsv1beta1.NullValuesOmit
+			newSecret.Spec.ScalarValues = sopssecretsv1beta1.ScalarValuesNormalize
+			newSecret.Data = `db:
+  port: 5432
+  tls: true
+  password: null
+hosts:
+- a
+- b
+---
+ratio: 0.5
+`
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Expect(createdSecret.Data).To(HaveKeyWithValue("db.port", []byte("5432")))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("db.tls", []byte("true")))
+			Expect(createdSecret.Data).ToNot(HaveKey("db.password"))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("hosts.1", []byte("b")))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("ratio", []byte("0.5")))
+		})
+
+		It("stores nested values as JSON", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Flatten = sopssecretsv1beta1.FlattenJSON
+			newSecret.Data = `config:
+  retries: 3
+token: abc
+`
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Expect(createdSecret.Data).To(HaveKeyWithValue("config", []byte(`{"retries":3}`)))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("token", []byte("abc")))
+		})
+
+		It("parses dotenv data", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Format = sopssecretsv1beta1.FormatDotenv
+			newSecret.Data = `# comment
+DB_USER=app
+DB_PASSWORD=a=b
+`
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Expect(createdSecret.Data).To(HaveLen(2))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("DB_USER", []byte("app")))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("DB_PASSWORD", []byte("a=b")))
+			Expect(mockedDecrytor.DecryptCalls()[0].S).To(Equal("dotenv"))
+		})
+
+		It("stores the whole file under fileKey", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Format = sopssecretsv1beta1.FormatBinary
+			newSecret.Spec.FileKey = "application.properties"
+			newSecret.Data = "db.user=app\n"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Expect(createdSecret.Data).To(Equal(map[string][]byte{"application.properties": []byte("db.user=app\n")}))
+		})
+
+		It("renders template data", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Template.Data = map[string]string{
+				"url":  "postgres://{{ .user }}:{{ .password | urlquery }}@db",
+				"user": "{{ upper .user }}",
+			}
+			newSecret.Data = `user: app
+password: p@ss
+`
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Expect(createdSecret.Data).To(HaveKeyWithValue("url", []byte("postgres://app:p%40ss@db")))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("user", []byte("APP")))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("password", []byte("p@ss")))
+		})
+
+		It("reports templates that fail to render", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Template.Data = map[string]string{"url": "{{ .missing }}"}
+			newSecret.Data = "user: app"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			Eventually(func() string {
+				_ = k8sClient.Get(ctx, types.NamespacedName{Name: newSecret.Name, Namespace: newSecret.Namespace}, fetchSopsSecret)
+				condition := meta.FindStatusCondition(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.RenderedCondition)
+				if condition == nil {
+					return ""
+				}
+				return condition.Reason
+			}, maxTimeout).Should(Equal(sopssecretsv1beta1.ReasonTemplateFailed))
+
+			Expect(meta.IsStatusConditionTrue(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.DecryptedCondition)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, getNamespacedName(), &corev1.Secret{})).ToNot(Succeed())
+		})
+
+		It("generates dockerconfigjson secrets", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Generator = &sopssecretsv1beta1.SopsSecretGenerator{
+				DockerConfigJSON: &sopssecretsv1beta1.DockerConfigJSONGenerator{Registry: "ghcr.io", PasswordKey: "token"},
+			}
+			newSecret.Data = `username: app
+token: secret
+`
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Expect(createdSecret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+			Expect(createdSecret.Data).To(HaveLen(1))
+			Expect(createdSecret.Data).To(HaveKeyWithValue(corev1.DockerConfigJsonKey,
+				[]byte(`{"auths":{"ghcr.io":{"username":"app","password":"secret","auth":"YXBwOnNlY3JldA=="}}}`)))
+		})
+
+		It("reports generators that don't match the declared type", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Type = corev1.SecretTypeOpaque
+			newSecret.Spec.Generator = &sopssecretsv1beta1.SopsSecretGenerator{
+				BasicAuth: &sopssecretsv1beta1.BasicAuthGenerator{},
+			}
+			newSecret.Data = "username: app"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			Eventually(func() bool {
+				_ = k8sClient.Get(ctx, types.NamespacedName{Name: newSecret.Name, Namespace: newSecret.Namespace}, fetchSopsSecret)
+				return meta.IsStatusConditionFalse(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.ValidCondition)
+			}, maxTimeout).Should(BeTrue())
+		})
+
+		It("reports secrets that don't match their type without writing them", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Type = corev1.SecretTypeTLS
+			newSecret.Data = "tls.crt: cert"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			Eventually(func() []sopssecretsv1beta1.SopsSecretNamespaceStatus {
+				_ = k8sClient.Get(ctx, types.NamespacedName{Name: newSecret.Name, Namespace: newSecret.Namespace}, fetchSopsSecret)
+				return fetchSopsSecret.Status.Namespaces
+			}, maxTimeout).Should(HaveLen(1))
+
+			Expect(fetchSopsSecret.Status.Namespaces[0].Reason).To(Equal(sopssecretsv1beta1.ReasonInvalidSecret))
+			Expect(fetchSopsSecret.Status.Namespaces[0].Message).To(ContainSubstring("tls.key"))
+			Eventually(func() []string {
+				return getEventReasons(newSecret.Name)
+			}, maxTimeout).Should(ContainElement(sopssecretsv1beta1.ReasonInvalidSecret))
+			Expect(k8sClient.Get(ctx, getNamespacedName(), &corev1.Secret{})).ToNot(Succeed())
+		})
+
+		It("writes configMapKeys to a configmap", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.ConfigMapKeys = []string{"glob:LOG_*"}
+			newSecret.Data = `LOG_LEVEL: debug
+PASSWORD: secret
+`
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+			Expect(createdSecret.Data).To(Equal(map[string][]byte{"PASSWORD": []byte("secret")}))
+
+			createdConfigMap := &corev1.ConfigMap{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdConfigMap)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+			Expect(createdConfigMap.Data).To(Equal(map[string]string{"LOG_LEVEL": "debug"}))
+			Expect(createdConfigMap.Labels).To(HaveKeyWithValue(controllers.OwnershipLabel, createdSecret.Labels[controllers.OwnershipLabel]))
+
+			// The configmap is removed again once no keys are routed to it.
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: newSecret.Name, Namespace: newSecret.Namespace}, fetchSopsSecret)).To(Succeed())
+			fetchSopsSecret.Spec.ConfigMapKeys = nil
+			Expect(k8sClient.Update(ctx, fetchSopsSecret)).To(Succeed())
+
+			Eventually(func() bool {
+				return k8serrors.IsNotFound(k8sClient.Get(ctx, getNamespacedName(), &corev1.ConfigMap{}))
+			}, maxTimeout).Should(BeTrue())
+			Eventually(func() map[string][]byte {
+				_ = k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+				return createdSecret.Data
+			}, maxTimeout).Should(HaveKey("LOG_LEVEL"))
+		})
+
+		It("decodes binary keys", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.BinaryKeys = []string{"glob:*.bin"}
+			newSecret.Data = `keystore.bin: /wAB
+plain: /wAB
+`
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Expect(createdSecret.Data).To(HaveKeyWithValue("keystore.bin", []byte{0xff, 0x00, 0x01}))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("plain", []byte("/wAB")))
+		})
+
 		It("Reconcile short-circuits on match", func() {
 			newSecret := getTestSopsSecret()
 			newSecretKey := types.NamespacedName{Name: newSecret.Name, Namespace: newSecret.Namespace}
@@ -213,6 +727,11 @@ var _ = Describe("sopssecret controller", func() {
 				Expect(err).ToNot(HaveOccurred())
 				return createdSecret.Data["secret"]
 			}, maxTimeout).Should(Equal([]byte("update")))
+
+			// Both out of band changes were restored, the namespace is new to this test.
+			Eventually(func() float64 {
+				return testutil.ToFloat64(controllers.DriftRestorations.WithLabelValues(currentNamespace))
+			}, maxTimeout).Should(BeNumerically(">=", 2))
 		})
 
 		It("does not overwrite ignored keys", func() {
@@ -246,19 +765,21 @@ var _ = Describe("sopssecret controller", func() {
 				return createdSecret.Data["notremoved"]
 			}, maxTimeout).Should(Equal([]byte("test")))
 			Expect(createdSecret.Data["notupdated"]).To(Equal([]byte("value")))
+
+			// Ignored keys are never part of the controller's apply, so it doesn't take them over.
+			for _, managedFields := range createdSecret.ManagedFields {
+				if managedFields.Manager == "sops-converter" && managedFields.Operation == metav1.ManagedFieldsOperationApply {
+					Expect(string(managedFields.FieldsV1.Raw)).ToNot(ContainSubstring("notremoved"))
+				}
+			}
 		})
 
-		It("annotations and labels behaviors", func() {
+		It("does not overwrite keys matching ignored patterns", func() {
 			newSecret := getTestSopsSecret()
 			newSecret.Data = "secret: update"
-
-			newSecret.Spec.Template.Annotations = map[string]string{
-				"test.annotation":   "value",
-				"test-annotation.2": "ok",
-			}
-			newSecret.Spec.Template.Labels = map[string]string{
-				"test.label":   "value",
-				"test-label.2": "ok",
+			newSecret.Spec.IgnoredKeys = []string{
+				"glob:*.generated",
+				"regex:tls\\.(crt|key)",
 			}
 
 			err := k8sClient.Create(ctx, newSecret)
@@ -270,17 +791,29 @@ var _ = Describe("sopssecret controller", func() {
 				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
 			}, maxTimeout).Should(Not(HaveOccurred()))
 
-			Expect(createdSecret.Annotations["test.annotation"]).To(Equal("value"))
-			Expect(createdSecret.Annotations["test-annotation.2"]).To(Equal("ok"))
-
-			Expect(createdSecret.Labels["test.label"]).To(Equal("value"))
-			Expect(createdSecret.Labels["test-label.2"]).To(Equal("ok"))
-
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, createdSecretKey, createdSecret)
+				if err != nil {
+					return err
+				}
+				createdSecret.Data["token.generated"] = []byte("other operator")
+				createdSecret.Data["tls.crt"] = []byte("cert")
+				createdSecret.Data["tls.crt.bak"] = []byte("not matched")
+				return k8sClient.Update(ctx, createdSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Eventually(func() map[string][]byte {
+				_ = k8sClient.Get(ctx, createdSecretKey, createdSecret)
+				return createdSecret.Data
+			}, maxTimeout).ShouldNot(HaveKey("tls.crt.bak"))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("token.generated", []byte("other operator")))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("tls.crt", []byte("cert")))
 		})
 
-		It("secret is deleted when sopssecret is deleted", func() {
+		It("only writes managed keys and leaves the others alone", func() {
 			newSecret := getTestSopsSecret()
-			newSecret.Data = "secret: update"
+			newSecret.Data = "password: managed\nextra: dropped"
+			newSecret.Spec.ManagedKeys = []string{"password"}
 
 			err := k8sClient.Create(ctx, newSecret)
 			Expect(err).ToNot(HaveOccurred())
@@ -290,25 +823,191 @@ var _ = Describe("sopssecret controller", func() {
 			Eventually(func() error {
 				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
 			}, maxTimeout).Should(Not(HaveOccurred()))
-
-			err = k8sClient.Delete(ctx, newSecret)
-			Expect(err).ToNot(HaveOccurred())
+			Expect(createdSecret.Data).To(HaveKeyWithValue("password", []byte("managed")))
+			Expect(createdSecret.Data).ToNot(HaveKey("extra"))
 
 			Eventually(func() error {
-				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
-			}, maxTimeout).Should(HaveOccurred())
+				err := k8sClient.Get(ctx, createdSecretKey, createdSecret)
+				if err != nil {
+					return err
+				}
+				createdSecret.Data["other"] = []byte("other writer")
+				return k8sClient.Update(ctx, createdSecret)
+			}, maxTimeout).Should(Succeed())
+
+			// Keys outside managedKeys are not part of the checksum, so changing them is not drift.
+			Consistently(func() []string {
+				return getEventReasons(newSecret.Name)
+			}, maxTimeout).ShouldNot(ContainElement(controllers.EventReasonDriftRestored))
+			_ = k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			Expect(createdSecret.Data).To(HaveKeyWithValue("other", []byte("other writer")))
+			Expect(createdSecret.Data).ToNot(HaveKey("extra"))
 		})
 
-		It("secret is not deleted when skipFinalizer spec is set", func() {
+		It("reports invalid key patterns", func() {
 			newSecret := getTestSopsSecret()
-			newSecret.Data = "secret: update"
-			newSecret.Spec.SkipFinalizers = true
+			newSecret.Data = "secret: invalid"
+			newSecret.Spec.ManagedKeys = []string{"regex:("}
 
 			err := k8sClient.Create(ctx, newSecret)
 			Expect(err).ToNot(HaveOccurred())
 
-			createdSecretKey := getNamespacedName()
-			createdSecret := &corev1.Secret{}
+			Eventually(func() string {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				condition := meta.FindStatusCondition(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.ValidCondition)
+				if condition == nil || condition.Status != metav1.ConditionFalse {
+					return ""
+				}
+				return condition.Reason
+			}, maxTimeout).Should(Equal(sopssecretsv1beta1.ReasonInvalidSpec))
+
+			Consistently(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), &corev1.Secret{})
+			}, 1).Should(HaveOccurred())
+		})
+
+		It("annotations and labels behaviors", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: update"
+
+			newSecret.Spec.Template.Annotations = map[string]string{
+				"test.annotation":   "value",
+				"test-annotation.2": "ok",
+			}
+			newSecret.Spec.Template.Labels = map[string]string{
+				"test.label":   "value",
+				"test-label.2": "ok",
+			}
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(Not(HaveOccurred()))
+
+			Expect(createdSecret.Annotations["test.annotation"]).To(Equal("value"))
+			Expect(createdSecret.Annotations["test-annotation.2"]).To(Equal("ok"))
+
+			Expect(createdSecret.Labels["test.label"]).To(Equal("value"))
+			Expect(createdSecret.Labels["test-label.2"]).To(Equal("ok"))
+
+		})
+
+		It("keeps annotations added by other tools", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: first"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+				if err != nil {
+					return err
+				}
+				createdSecret.Annotations["other-tool/reloaded-at"] = "yesterday"
+				createdSecret.Data["out-of-band"] = []byte("added")
+				return k8sClient.Update(ctx, createdSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Eventually(func() error {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				err := k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				if err != nil {
+					return err
+				}
+				fetchSopsSecret.Data = "secret: second"
+				return k8sClient.Update(ctx, fetchSopsSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Eventually(func() []byte {
+				_ = k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+				return createdSecret.Data["secret"]
+			}, maxTimeout).Should(Equal([]byte("second")))
+			Expect(createdSecret.Data).ToNot(HaveKey("out-of-band"))
+			Expect(createdSecret.Annotations).To(HaveKeyWithValue("other-tool/reloaded-at", "yesterday"))
+		})
+
+		It("secret is deleted when sopssecret is deleted", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: update"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(Not(HaveOccurred()))
+
+			err = k8sClient.Delete(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(HaveOccurred())
+		})
+
+		It("deletes the secrets in every namespace when sopssecret is deleted", func() {
+			otherNamespaces := []string{getRandomString(), getRandomString()}
+			for _, namespace := range otherNamespaces {
+				createNamespace(namespace)
+			}
+
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: everywhere"
+			newSecret.Spec.Template.Namespaces = append([]string{currentNamespace}, otherNamespaces...)
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, namespace := range newSecret.Spec.Template.Namespaces {
+				createdSecretKey := types.NamespacedName{Name: currentObjectName, Namespace: namespace}
+				Eventually(func() error {
+					return k8sClient.Get(ctx, createdSecretKey, &corev1.Secret{})
+				}, maxTimeout).ShouldNot(HaveOccurred())
+			}
+
+			err = k8sClient.Delete(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), &sopssecretsv1beta1.SopsSecret{})
+			}, maxTimeout).Should(HaveOccurred())
+
+			for _, namespace := range newSecret.Spec.Template.Namespaces {
+				createdSecretKey := types.NamespacedName{Name: currentObjectName, Namespace: namespace}
+				Eventually(func() bool {
+					err := k8sClient.Get(ctx, createdSecretKey, &corev1.Secret{})
+					return k8serrors.IsNotFound(err)
+				}, maxTimeout).Should(BeTrue())
+				Consistently(func() bool {
+					err := k8sClient.Get(ctx, createdSecretKey, &corev1.Secret{})
+					return k8serrors.IsNotFound(err)
+				}, 1).Should(BeTrue())
+			}
+		})
+
+		It("secret is not deleted when skipFinalizer spec is set", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: update"
+			newSecret.Spec.SkipFinalizers = true
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
 			Eventually(func() error {
 				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
 			}, maxTimeout).Should(Not(HaveOccurred()))
@@ -321,6 +1020,35 @@ var _ = Describe("sopssecret controller", func() {
 			}, maxTimeout).ShouldNot(HaveOccurred())
 		})
 
+		It("releases the secret when deletionPolicy is OrphanWithLabelRemoval", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: handover"
+			newSecret.Spec.NamespaceDeletionPolicies = map[string]sopssecretsv1beta1.DeletionPolicy{
+				currentNamespace: sopssecretsv1beta1.DeletionPolicyOrphanWithLabelRemoval,
+			}
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(Not(HaveOccurred()))
+			Expect(createdSecret.Labels).To(HaveKey(controllers.OwnershipLabel))
+
+			err = k8sClient.Delete(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() map[string]string {
+				err := k8sClient.Get(ctx, createdSecretKey, createdSecret)
+				Expect(err).ToNot(HaveOccurred())
+				return createdSecret.Labels
+			}, maxTimeout).ShouldNot(HaveKey(controllers.OwnershipLabel))
+			Expect(createdSecret.Annotations).ToNot(HaveKey(controllers.OwnerNameAnnotation))
+			Expect(createdSecret.Data).To(HaveKeyWithValue("secret", []byte("handover")))
+		})
+
 		It("Cross namespace reconcile", func() {
 			newSecret := getTestSopsSecret()
 			newSecret.Spec.Template.Namespaces = []string{
@@ -365,6 +1093,516 @@ var _ = Describe("sopssecret controller", func() {
 			Expect(createdSecret.Data["secret"]).To(Equal([]byte("exists")))
 		})
 
+		It("decrypts once when targeting multiple namespaces", func() {
+			targetNamespaces := []string{getRandomString(), getRandomString(), getRandomString()}
+			for _, targetNamespace := range targetNamespaces {
+				createNamespace(targetNamespace)
+			}
+
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Template.Namespaces = targetNamespaces
+			newSecret.Data = "secret: fanout"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
+			for _, targetNamespace := range targetNamespaces {
+				createdSecretKey.Namespace = targetNamespace
+				Eventually(func() error {
+					return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+				}, maxTimeout).Should(Not(HaveOccurred()))
+				Expect(createdSecret.Data["secret"]).To(Equal([]byte("fanout")))
+			}
+
+			Consistently(func() int {
+				return len(mockedDecrytor.DecryptCalls())
+			}, maxTimeout).Should(Equal(1))
+		})
+
+		It("targets namespaces matching the namespace selector", func() {
+			selectorLabels := map[string]string{"sops-converter-test": currentObjectName}
+			matchingNamespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   getRandomString(),
+					Labels: selectorLabels,
+				},
+			}
+			err := k8sClient.Create(ctx, matchingNamespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Template.NamespaceSelector = &metav1.LabelSelector{MatchLabels: selectorLabels}
+			newSecret.Data = "secret: selected"
+
+			err = k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
+			createdSecretKey.Namespace = matchingNamespace.Name
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(Not(HaveOccurred()))
+
+			// A namespace created later is picked up by the namespace watch.
+			laterNamespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   getRandomString(),
+					Labels: selectorLabels,
+				},
+			}
+			err = k8sClient.Create(ctx, laterNamespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey.Namespace = laterNamespace.Name
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(Not(HaveOccurred()))
+
+			// The selector never matched the SopsSecret's own namespace.
+			Consistently(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).Should(HaveOccurred())
+
+			// Namespaces that stop matching are cleaned up.
+			_ = k8sClient.Get(ctx, types.NamespacedName{Name: laterNamespace.Name}, laterNamespace)
+			laterNamespace.Labels = nil
+			err = k8sClient.Update(ctx, laterNamespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(HaveOccurred())
+		})
+
+		It("reports an invalid namespace selector and can still be deleted", func() {
+			selectorLabels := map[string]string{"sops-converter-test": currentObjectName}
+			matchingNamespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   getRandomString(),
+					Labels: selectorLabels,
+				},
+			}
+			err := k8sClient.Create(ctx, matchingNamespace)
+			Expect(err).ToNot(HaveOccurred())
+
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Template.NamespaceSelector = &metav1.LabelSelector{MatchLabels: selectorLabels}
+			newSecret.Data = "secret: selected"
+
+			err = k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := types.NamespacedName{Name: currentObjectName, Namespace: matchingNamespace.Name}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, &corev1.Secret{})
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+			fetchSopsSecret.Spec.Template.NamespaceSelector = &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "sops-converter-test", Operator: "Equals", Values: []string{currentObjectName}},
+				},
+			}
+			err = k8sClient.Update(ctx, fetchSopsSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() string {
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				condition := meta.FindStatusCondition(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.ValidCondition)
+				if condition == nil || condition.Status != metav1.ConditionFalse {
+					return ""
+				}
+				return condition.Reason
+			}, maxTimeout).Should(Equal(sopssecretsv1beta1.ReasonInvalidSpec))
+
+			// Nothing is garbage collected while the targets are unknown.
+			Expect(k8sClient.Get(ctx, createdSecretKey, &corev1.Secret{})).To(Succeed())
+
+			err = k8sClient.Delete(ctx, fetchSopsSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, getNamespacedName(), &sopssecretsv1beta1.SopsSecret{})
+				return k8serrors.IsNotFound(err)
+			}, maxTimeout).Should(BeTrue())
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, createdSecretKey, &corev1.Secret{})
+				return k8serrors.IsNotFound(err)
+			}, maxTimeout).Should(BeTrue())
+		})
+
+		It("only targets namespaces allowed by a SopsSecretPolicy", func() {
+			allowedNamespace := getRandomString()
+			deniedNamespace := getRandomString()
+			createNamespace(allowedNamespace)
+			createNamespace(deniedNamespace)
+
+			policy := &sopssecretsv1beta1.SopsSecretPolicy{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: currentObjectName,
+				},
+				Spec: sopssecretsv1beta1.SopsSecretPolicySpec{
+					Rules: []sopssecretsv1beta1.SopsSecretPolicyRule{
+						{
+							SourceNamespaces: []string{currentNamespace},
+							TargetNamespaces: []string{allowedNamespace},
+						},
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, policy)
+			Expect(err).ToNot(HaveOccurred())
+			// Policies are cluster wide, don't let this one leak into other tests.
+			defer func() {
+				Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+			}()
+
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Template.Namespaces = []string{allowedNamespace, deniedNamespace}
+			newSecret.Data = "secret: policy"
+
+			err = k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
+			createdSecretKey.Namespace = allowedNamespace
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(Not(HaveOccurred()))
+
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			Eventually(func() bool {
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				return meta.IsStatusConditionFalse(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.TargetsAllowedCondition)
+			}, maxTimeout).Should(BeTrue())
+
+			createdSecretKey.Namespace = deniedNamespace
+			Consistently(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(HaveOccurred())
+		})
+
+		It("restores drift on secrets of SopsSecrets with dots in their name", func() {
+			currentObjectName = fmt.Sprintf("db.credentials.%s", getRandomString())
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: dotted"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(Not(HaveOccurred()))
+
+			Expect(createdSecret.Annotations[controllers.OwnerNameAnnotation]).To(Equal(currentObjectName))
+			Expect(createdSecret.Annotations[controllers.OwnerNamespaceAnnotation]).To(Equal(currentNamespace))
+
+			createdSecret.Data["secret"] = []byte("changed")
+			err = k8sClient.Update(ctx, createdSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() []byte {
+				err = k8sClient.Get(ctx, createdSecretKey, createdSecret)
+				Expect(err).ToNot(HaveOccurred())
+				return createdSecret.Data["secret"]
+			}, maxTimeout).Should(Equal([]byte("dotted")))
+		})
+
+		It("supports names longer than the label value limit", func() {
+			currentObjectName = fmt.Sprintf("%s-%s-%s-%s-%s", getRandomString(), getRandomString(), getRandomString(), getRandomString(), getRandomString())
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: long"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, createdSecretKey, createdSecret)
+			}, maxTimeout).Should(Not(HaveOccurred()))
+
+			Expect(len(createdSecret.Labels[controllers.OwnershipLabel])).To(BeNumerically("<=", 63))
+			Expect(createdSecret.Data["secret"]).To(Equal([]byte("long")))
+		})
+
+		It("migrates secrets carrying the legacy ownership label", func() {
+			legacyLabelValue := fmt.Sprintf("%s.%s", currentObjectName, currentNamespace)
+			legacySecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      currentObjectName,
+					Namespace: currentNamespace,
+					Labels: map[string]string{
+						controllers.OwnershipLabel: legacyLabelValue,
+					},
+				},
+				Data: map[string][]byte{
+					"secret": []byte("legacy"),
+				},
+			}
+			err := k8sClient.Create(ctx, legacySecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: migrated"
+
+			err = k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecretKey := getNamespacedName()
+			createdSecret := &corev1.Secret{}
+			Eventually(func() []byte {
+				err = k8sClient.Get(ctx, createdSecretKey, createdSecret)
+				Expect(err).ToNot(HaveOccurred())
+				return createdSecret.Data["secret"]
+			}, maxTimeout).Should(Equal([]byte("migrated")))
+
+			Expect(createdSecret.Labels[controllers.OwnershipLabel]).ToNot(Equal(legacyLabelValue))
+			Expect(createdSecret.Annotations[controllers.OwnerNameAnnotation]).To(Equal(currentObjectName))
+		})
+
+		It("does not adopt existing secrets by default", func() {
+			existingSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      currentObjectName,
+					Namespace: currentNamespace,
+				},
+				Data: map[string][]byte{
+					"secret": []byte("handmade"),
+				},
+			}
+			err := k8sClient.Create(ctx, existingSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: managed"
+
+			err = k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			Eventually(func() bool {
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				return meta.IsStatusConditionTrue(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.ConflictCondition)
+			}, maxTimeout).Should(BeTrue())
+
+			createdSecret := &corev1.Secret{}
+			err = k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(createdSecret.Data["secret"]).To(Equal([]byte("handmade")))
+			Expect(testutil.ToFloat64(controllers.SkippedUnowned.WithLabelValues(currentNamespace, sopssecretsv1beta1.ReasonNotOwned))).To(BeNumerically(">=", 1))
+		})
+
+		It("adopts existing secrets when adoptionPolicy is Always", func() {
+			existingSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      currentObjectName,
+					Namespace: currentNamespace,
+				},
+				Data: map[string][]byte{
+					"secret": []byte("handmade"),
+				},
+			}
+			err := k8sClient.Create(ctx, existingSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.AdoptionPolicy = sopssecretsv1beta1.AdoptionPolicyAlways
+			newSecret.Data = "secret: managed"
+
+			err = k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() []byte {
+				err = k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+				Expect(err).ToNot(HaveOccurred())
+				return createdSecret.Data["secret"]
+			}, maxTimeout).Should(Equal([]byte("managed")))
+
+			Expect(createdSecret.Labels).To(HaveKey(controllers.OwnershipLabel))
+			Expect(createdSecret.Annotations).To(HaveKey(controllers.AdoptedChecksumAnnotation))
+		})
+
+		It("migrates between ownership modes", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.OwnershipMode = sopssecretsv1beta1.OwnershipModeOwnerReference
+			newSecret.Spec.Template.Namespaces = []string{currentNamespace, "default"}
+			newSecret.Data = "secret: owned"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() []metav1.OwnerReference {
+				_ = k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+				return createdSecret.OwnerReferences
+			}, maxTimeout).Should(HaveLen(1))
+			Expect(createdSecret.OwnerReferences[0].UID).To(Equal(newSecret.UID))
+			Expect(*createdSecret.OwnerReferences[0].Controller).To(BeTrue())
+			Expect(createdSecret.Labels).To(HaveKey(controllers.OwnershipLabel))
+
+			// Owner references can't cross namespaces, the label is all there is.
+			crossNamespaceSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: currentObjectName, Namespace: "default"}, crossNamespaceSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+			Expect(crossNamespaceSecret.OwnerReferences).To(BeEmpty())
+			Expect(crossNamespaceSecret.Labels).To(HaveKey(controllers.OwnershipLabel))
+
+			Eventually(func() error {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				err := k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				if err != nil {
+					return err
+				}
+				fetchSopsSecret.Spec.OwnershipMode = sopssecretsv1beta1.OwnershipModeLabel
+				return k8sClient.Update(ctx, fetchSopsSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Eventually(func() []metav1.OwnerReference {
+				_ = k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+				return createdSecret.OwnerReferences
+			}, maxTimeout).Should(BeEmpty())
+			Expect(createdSecret.Labels).To(HaveKey(controllers.OwnershipLabel))
+		})
+
+		It("leaves secrets alone while suspended", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: frozen"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			createdSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Eventually(func() error {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				err := k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				if err != nil {
+					return err
+				}
+				fetchSopsSecret.Spec.Suspend = true
+				return k8sClient.Update(ctx, fetchSopsSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Eventually(func() bool {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				return meta.IsStatusConditionTrue(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.SuspendedCondition)
+			}, maxTimeout).Should(BeTrue())
+
+			// A spec change while suspended is neither observed nor reported as ready.
+			fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				if err != nil {
+					return err
+				}
+				fetchSopsSecret.Data = "secret: edited"
+				return k8sClient.Update(ctx, fetchSopsSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Consistently(func() bool {
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				ready := meta.FindStatusCondition(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.ReadyCondition)
+				return fetchSopsSecret.Status.ObservedGeneration < fetchSopsSecret.Generation &&
+					ready != nil && ready.Status == metav1.ConditionUnknown && ready.Reason == sopssecretsv1beta1.ReasonSuspended
+			}, 2).Should(BeTrue())
+
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+				if err != nil {
+					return err
+				}
+				createdSecret.Data["secret"] = []byte("changed during incident")
+				return k8sClient.Update(ctx, createdSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Consistently(func() []byte {
+				_ = k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+				return createdSecret.Data["secret"]
+			}, 2).Should(Equal([]byte("changed during incident")))
+
+			// Resuming writes the edited data and reports the object as ready again.
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				if err != nil {
+					return err
+				}
+				fetchSopsSecret.Spec.Suspend = false
+				return k8sClient.Update(ctx, fetchSopsSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Eventually(func() bool {
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				return fetchSopsSecret.Status.ObservedGeneration == fetchSopsSecret.Generation &&
+					meta.IsStatusConditionTrue(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.ReadyCondition)
+			}, maxTimeout).Should(BeTrue())
+			Expect(meta.FindStatusCondition(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.SuspendedCondition)).To(BeNil())
+			_ = k8sClient.Get(ctx, getNamespacedName(), createdSecret)
+			Expect(createdSecret.Data["secret"]).To(Equal([]byte("edited")))
+		})
+
+		It("decrypts again when a reconcile is requested", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Data = "secret: rotated"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() bool {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				return meta.IsStatusConditionTrue(fetchSopsSecret.Status.Conditions, sopssecretsv1beta1.ReadyCondition)
+			}, maxTimeout).Should(BeTrue())
+			decryptCalls := len(mockedDecrytor.DecryptCalls())
+
+			Eventually(func() error {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				err := k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				if err != nil {
+					return err
+				}
+				fetchSopsSecret.Annotations[controllers.ReconcileRequestedAtAnnotation] = "now"
+				return k8sClient.Update(ctx, fetchSopsSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Eventually(func() string {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				_ = k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				return fetchSopsSecret.Status.LastHandledReconcileAt
+			}, maxTimeout).Should(Equal("now"))
+			Expect(len(mockedDecrytor.DecryptCalls())).To(BeNumerically(">", decryptCalls))
+		})
+
+		It("requeues after the refresh interval", func() {
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.RefreshInterval = &metav1.Duration{Duration: time.Minute}
+			newSecret.Data = "secret: refresh"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			Eventually(func() time.Duration {
+				res, err := usedReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: getNamespacedName()})
+				Expect(err).ToNot(HaveOccurred())
+				return res.RequeueAfter
+			}, maxTimeout).Should(BeNumerically(">=", time.Minute))
+			res, _ := usedReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: getNamespacedName()})
+			Expect(res.RequeueAfter).To(BeNumerically("<=", time.Minute+6*time.Second))
+		})
+
 		It("Cross namespace garbage collection", func() {
 			newSecret := getTestSopsSecret()
 			newSecret.Spec.Template.Namespaces = []string{
@@ -401,6 +1639,44 @@ var _ = Describe("sopssecret controller", func() {
 			}, maxTimeout).Should(HaveOccurred())
 
 		})
+
+		It("applies the deletion policy to namespaces no longer targeted", func() {
+			releasedNamespace := getRandomString()
+			createNamespace(releasedNamespace)
+
+			newSecret := getTestSopsSecret()
+			newSecret.Spec.Template.Namespaces = []string{currentNamespace, releasedNamespace}
+			newSecret.Spec.NamespaceDeletionPolicies = map[string]sopssecretsv1beta1.DeletionPolicy{
+				releasedNamespace: sopssecretsv1beta1.DeletionPolicyOrphanWithLabelRemoval,
+			}
+			newSecret.Data = "secret: kept"
+
+			err := k8sClient.Create(ctx, newSecret)
+			Expect(err).ToNot(HaveOccurred())
+
+			releasedSecretKey := types.NamespacedName{Name: currentObjectName, Namespace: releasedNamespace}
+			releasedSecret := &corev1.Secret{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, releasedSecretKey, releasedSecret)
+			}, maxTimeout).ShouldNot(HaveOccurred())
+
+			Eventually(func() error {
+				fetchSopsSecret := &sopssecretsv1beta1.SopsSecret{}
+				err := k8sClient.Get(ctx, getNamespacedName(), fetchSopsSecret)
+				if err != nil {
+					return err
+				}
+				fetchSopsSecret.Spec.Template.Namespaces = []string{currentNamespace}
+				return k8sClient.Update(ctx, fetchSopsSecret)
+			}, maxTimeout).Should(Succeed())
+
+			Eventually(func() map[string]string {
+				err := k8sClient.Get(ctx, releasedSecretKey, releasedSecret)
+				Expect(err).ToNot(HaveOccurred())
+				return releasedSecret.Labels
+			}, maxTimeout).ShouldNot(HaveKey(controllers.OwnershipLabel))
+			Expect(releasedSecret.Data).To(HaveKeyWithValue("secret", []byte("kept")))
+		})
 	})
 })
 
@@ -416,6 +1692,20 @@ func getTestSopsSecret() *sopssecretsv1beta1.SopsSecret {
 	}
 }
 
+func getEventReasons(involvedObjectName string) []string {
+	eventList := &corev1.EventList{}
+	err := k8sClient.List(context.Background(), eventList, client.InNamespace(currentNamespace))
+	Expect(err).ToNot(HaveOccurred())
+
+	var reasons []string
+	for _, event := range eventList.Items {
+		if event.InvolvedObject.Name == involvedObjectName {
+			reasons = append(reasons, event.Reason)
+		}
+	}
+	return reasons
+}
+
 func getNamespacedName() types.NamespacedName {
 	return types.NamespacedName{
 		Name:      currentObjectName,
@@ -0,0 +1,267 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/dhouti/sops-converter/api/v1beta1"
+)
+
+// updateStatus records the per-namespace outcomes and derived conditions on the SopsSecret.
+// The status subresource is only patched when something actually changed, otherwise every
+// reconcile would trigger another one.
+func (r *SopsSecretReconciler) updateStatus(ctx context.Context, obj sopsSecretObject, namespaceStatuses []secretsv1beta1.SopsSecretNamespaceStatus) error {
+	return r.patchStatus(ctx, obj, namespaceStatuses, nil, false)
+}
+
+// updateHandledStatus is updateStatus after every target was reconciled without an error, which also marks a requested reconcile as handled.
+// Until then every attempt, retries included, is still treated as requested.
+func (r *SopsSecretReconciler) updateHandledStatus(ctx context.Context, obj sopsSecretObject, namespaceStatuses []secretsv1beta1.SopsSecretNamespaceStatus) error {
+	return r.patchStatus(ctx, obj, namespaceStatuses, nil, true)
+}
+
+// updateInvalidSpecStatus reports specErr on every target namespace and on the Valid condition, which carries it even without any target.
+func (r *SopsSecretReconciler) updateInvalidSpecStatus(ctx context.Context, obj sopsSecretObject, targetNamespaces []string, specErr error) error {
+	return r.patchStatus(ctx, obj, invalidSpecStatuses(obj, targetNamespaces, specErr), specErr, false)
+}
+
+func (r *SopsSecretReconciler) patchStatus(ctx context.Context, obj sopsSecretObject, namespaceStatuses []secretsv1beta1.SopsSecretNamespaceStatus, specErr error, handled bool) error {
+	// Nothing to report on an object that is going away.
+	if !obj.GetDeletionTimestamp().IsZero() {
+		return nil
+	}
+
+	base := obj.DeepCopyObject().(sopsSecretObject)
+	status := obj.GetStatus()
+	status.ObservedGeneration = obj.GetGeneration()
+	status.Namespaces = namespaceStatuses
+	for _, namespaceStatus := range namespaceStatuses {
+		if namespaceStatus.LastSyncedTime == nil {
+			continue
+		}
+		if status.LastSyncedTime == nil || status.LastSyncedTime.Before(namespaceStatus.LastSyncedTime) {
+			status.LastSyncedTime = namespaceStatus.LastSyncedTime
+		}
+	}
+	if requestedAt, ok := obj.GetAnnotations()[ReconcileRequestedAtAnnotation]; ok && handled {
+		status.LastHandledReconcileAt = requestedAt
+	}
+	setStatusConditions(status, obj.GetGeneration(), specErr)
+	meta.RemoveStatusCondition(&status.Conditions, secretsv1beta1.SuspendedCondition)
+
+	if equality.Semantic.DeepEqual(base.GetStatus(), status) {
+		return nil
+	}
+	return r.Status().Patch(ctx, obj, client.MergeFrom(base))
+}
+
+// updateSuspendedStatus marks obj as suspended, leaving the outcome of the last reconcile in place.
+// Nothing of the current spec was acted upon, so observedGeneration stays and Ready is Unknown until the object is resumed.
+func (r *SopsSecretReconciler) updateSuspendedStatus(ctx context.Context, obj sopsSecretObject) error {
+	base := obj.DeepCopyObject().(sopsSecretObject)
+	status := obj.GetStatus()
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               secretsv1beta1.SuspendedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             secretsv1beta1.ReasonSuspended,
+		Message:            "Reconciliation is suspended, no Secret is written",
+		ObservedGeneration: obj.GetGeneration(),
+	})
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               secretsv1beta1.ReadyCondition,
+		Status:             metav1.ConditionUnknown,
+		Reason:             secretsv1beta1.ReasonSuspended,
+		Message:            "Reconciliation is suspended, the Secrets may not match the spec",
+		ObservedGeneration: obj.GetGeneration(),
+	})
+
+	if equality.Semantic.DeepEqual(base.GetStatus(), status) {
+		return nil
+	}
+	return r.Status().Patch(ctx, obj, client.MergeFrom(base))
+}
+
+// reconcileRequested reports whether the reconcile-requested-at annotation of obj holds a value not acted upon yet.
+func reconcileRequested(obj sopsSecretObject) bool {
+	requestedAt, ok := obj.GetAnnotations()[ReconcileRequestedAtAnnotation]
+	return ok && requestedAt != obj.GetStatus().LastHandledReconcileAt
+}
+
+// setStatusConditions derives the Valid, Decrypted, Rendered, TargetsAllowed, Synced and Ready conditions from the namespace outcomes and specErr.
+func setStatusConditions(status *secretsv1beta1.SopsSecretStatus, generation int64, specErr error) {
+	valid := metav1.Condition{
+		Type:               secretsv1beta1.ValidCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             secretsv1beta1.ReasonSucceeded,
+		Message:            "Spec is valid",
+		ObservedGeneration: generation,
+	}
+	decrypted := metav1.Condition{
+		Type:               secretsv1beta1.DecryptedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             secretsv1beta1.ReasonSucceeded,
+		Message:            "Data decrypted successfully",
+		ObservedGeneration: generation,
+	}
+	rendered := metav1.Condition{
+		Type:               secretsv1beta1.RenderedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             secretsv1beta1.ReasonSucceeded,
+		Message:            "Template data and generator applied successfully",
+		ObservedGeneration: generation,
+	}
+	targetsAllowed := metav1.Condition{
+		Type:               secretsv1beta1.TargetsAllowedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             secretsv1beta1.ReasonSucceeded,
+		Message:            "All target namespaces are allowed",
+		ObservedGeneration: generation,
+	}
+	synced := metav1.Condition{
+		Type:               secretsv1beta1.SyncedCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             secretsv1beta1.ReasonSucceeded,
+		Message:            fmt.Sprintf("%d target Secret(s) in sync", len(status.Namespaces)),
+		ObservedGeneration: generation,
+	}
+
+	conflict := metav1.Condition{
+		Type:               secretsv1beta1.ConflictCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             secretsv1beta1.ReasonNoConflict,
+		Message:            "No conflicting Secrets",
+		ObservedGeneration: generation,
+	}
+
+	if specErr != nil {
+		valid.Status = metav1.ConditionFalse
+		valid.Reason = secretsv1beta1.ReasonInvalidSpec
+		valid.Message = specErr.Error()
+	}
+
+	var unsynced, denied, conflicting []string
+	for _, namespaceStatus := range status.Namespaces {
+		switch namespaceStatus.State {
+		case secretsv1beta1.SyncStateDenied:
+			denied = append(denied, namespaceStatus.Namespace)
+		case secretsv1beta1.SyncStateSkipped:
+			conflicting = append(conflicting, namespaceStatus.Namespace)
+			conflict.Status = metav1.ConditionTrue
+			conflict.Reason = namespaceStatus.Reason
+		}
+
+		if namespaceStatus.Reason == secretsv1beta1.ReasonInvalidSpec {
+			valid.Status = metav1.ConditionFalse
+			valid.Reason = namespaceStatus.Reason
+			valid.Message = namespaceStatus.Message
+		}
+		if namespaceStatus.Reason == secretsv1beta1.ReasonTemplateFailed || namespaceStatus.Reason == secretsv1beta1.ReasonGeneratorFailed {
+			rendered.Status = metav1.ConditionFalse
+			rendered.Reason = namespaceStatus.Reason
+			rendered.Message = namespaceStatus.Message
+		} else if _, ok := decryptErrorClasses[namespaceStatus.Reason]; ok {
+			decrypted.Status = metav1.ConditionFalse
+			decrypted.Reason = namespaceStatus.Reason
+			decrypted.Message = namespaceStatus.Message
+		}
+
+		if namespaceStatus.State == secretsv1beta1.SyncStateSynced {
+			continue
+		}
+		unsynced = append(unsynced, namespaceStatus.Namespace)
+		synced.Status = metav1.ConditionFalse
+		// A failure takes precedence over a skipped namespace when picking the reason.
+		if synced.Reason != secretsv1beta1.ReasonSyncFailed {
+			synced.Reason = namespaceStatus.Reason
+		}
+		if namespaceStatus.State == secretsv1beta1.SyncStateFailed {
+			synced.Reason = secretsv1beta1.ReasonSyncFailed
+		}
+	}
+	if len(unsynced) > 0 {
+		synced.Message = fmt.Sprintf("Secret not in sync in namespaces: %s", strings.Join(unsynced, ", "))
+	}
+	if len(conflicting) > 0 {
+		conflict.Message = fmt.Sprintf("Existing Secrets not owned by this object in namespaces: %s", strings.Join(conflicting, ", "))
+	}
+	if len(denied) > 0 {
+		targetsAllowed.Status = metav1.ConditionFalse
+		targetsAllowed.Reason = secretsv1beta1.ReasonTargetDenied
+		targetsAllowed.Message = fmt.Sprintf("Target namespaces denied: %s", strings.Join(denied, ", "))
+	}
+
+	ready := metav1.Condition{
+		Type:               secretsv1beta1.ReadyCondition,
+		Status:             metav1.ConditionTrue,
+		Reason:             secretsv1beta1.ReasonSucceeded,
+		Message:            synced.Message,
+		ObservedGeneration: generation,
+	}
+	for _, condition := range []metav1.Condition{valid, decrypted, rendered, targetsAllowed, synced} {
+		if condition.Status != metav1.ConditionTrue {
+			ready.Status = condition.Status
+			ready.Reason = condition.Reason
+			ready.Message = condition.Message
+			break
+		}
+	}
+
+	meta.SetStatusCondition(&status.Conditions, valid)
+	meta.SetStatusCondition(&status.Conditions, decrypted)
+	meta.SetStatusCondition(&status.Conditions, rendered)
+	meta.SetStatusCondition(&status.Conditions, targetsAllowed)
+	meta.SetStatusCondition(&status.Conditions, conflict)
+	meta.SetStatusCondition(&status.Conditions, synced)
+	meta.SetStatusCondition(&status.Conditions, ready)
+}
+
+func findNamespaceStatus(namespaceStatuses []secretsv1beta1.SopsSecretNamespaceStatus, namespace string) *secretsv1beta1.SopsSecretNamespaceStatus {
+	for i := range namespaceStatuses {
+		if namespaceStatuses[i].Namespace == namespace {
+			return &namespaceStatuses[i]
+		}
+	}
+	return nil
+}
+
+func failedNamespaceStatus(namespaceStatus secretsv1beta1.SopsSecretNamespaceStatus, reason string, err error) secretsv1beta1.SopsSecretNamespaceStatus {
+	namespaceStatus.State = secretsv1beta1.SyncStateFailed
+	namespaceStatus.Reason = reason
+	namespaceStatus.Message = err.Error()
+	return namespaceStatus
+}
+
+// invalidSpecStatuses marks every target namespace as failed because of an invalid spec.
+func invalidSpecStatuses(obj sopsSecretObject, targetNamespaces []string, err error) []secretsv1beta1.SopsSecretNamespaceStatus {
+	namespaceStatuses := make([]secretsv1beta1.SopsSecretNamespaceStatus, 0, len(targetNamespaces))
+	for _, targetNamespace := range targetNamespaces {
+		namespaceStatus := secretsv1beta1.SopsSecretNamespaceStatus{Namespace: targetNamespace}
+		if previous := findNamespaceStatus(obj.GetStatus().Namespaces, targetNamespace); previous != nil {
+			namespaceStatus.LastSyncedTime = previous.LastSyncedTime
+		}
+		namespaceStatuses = append(namespaceStatuses, failedNamespaceStatus(namespaceStatus, secretsv1beta1.ReasonInvalidSpec, err))
+	}
+	return namespaceStatuses
+}
@@ -44,6 +44,7 @@ var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
 var usedReconciler *controllers.SopsSecretReconciler
+var usedClusterReconciler *controllers.ClusterSopsSecretReconciler
 
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -77,13 +78,25 @@ var _ = BeforeSuite(func(done Done) {
 	Expect(err).ToNot(HaveOccurred())
 
 	usedReconciler = &controllers.SopsSecretReconciler{
-		Client: k8sManager.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("SopsSecret"),
-		Scheme: scheme.Scheme,
+		Client:   k8sManager.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("SopsSecret"),
+		Scheme:   scheme.Scheme,
+		Recorder: k8sManager.GetEventRecorderFor("sops-converter"),
 	}
 	err = usedReconciler.SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	usedClusterReconciler = &controllers.ClusterSopsSecretReconciler{
+		SopsSecretReconciler: controllers.SopsSecretReconciler{
+			Client:   k8sManager.GetClient(),
+			Log:      ctrl.Log.WithName("controllers").WithName("ClusterSopsSecret"),
+			Scheme:   scheme.Scheme,
+			Recorder: k8sManager.GetEventRecorderFor("sops-converter"),
+		},
+	}
+	err = usedClusterReconciler.SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	go func() {
 		err = k8sManager.Start(ctrl.SetupSignalHandler())
 		fmt.Printf("????%#v\n", err.Error())
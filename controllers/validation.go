@@ -0,0 +1,113 @@
+/*
+Copyright © 2020 Rex Via  l.rex.via@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// requiredSecretKeys are the data keys the API server requires for the built-in Secret types.
+var requiredSecretKeys = map[corev1.SecretType][]string{
+	corev1.SecretTypeTLS:              {corev1.TLSCertKey, corev1.TLSPrivateKeyKey},
+	corev1.SecretTypeDockerConfigJson: {corev1.DockerConfigJsonKey},
+	corev1.SecretTypeDockercfg:        {corev1.DockerConfigKey},
+	corev1.SecretTypeSSHAuth:          {corev1.SSHAuthPrivateKey},
+}
+
+// validateSecret mirrors the checks of the API server on the data of a Secret, so problems are reported precisely instead of as a rejected write.
+func validateSecret(secretType corev1.SecretType, annotations map[string]string, data map[string][]byte) error {
+	dataPath := field.NewPath("data")
+	sizes := make(map[string]int, len(data))
+	for key, value := range data {
+		sizes[key] = len(value)
+	}
+	allErrs := validateDataKeys(dataPath, sizes)
+
+	for _, key := range requiredSecretKeys[secretType] {
+		if _, ok := data[key]; !ok {
+			allErrs = append(allErrs, field.Required(dataPath.Key(key), fmt.Sprintf("required for type %s", secretType)))
+		}
+	}
+
+	switch secretType {
+	case corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg:
+		key := corev1.DockerConfigJsonKey
+		if secretType == corev1.SecretTypeDockercfg {
+			key = corev1.DockerConfigKey
+		}
+		if value, ok := data[key]; ok {
+			var dockerConfig map[string]interface{}
+			if err := json.Unmarshal(value, &dockerConfig); err != nil {
+				allErrs = append(allErrs, field.Invalid(dataPath.Key(key), "<secret contents redacted>", err.Error()))
+			}
+		}
+	case corev1.SecretTypeBasicAuth:
+		_, hasUsername := data[corev1.BasicAuthUsernameKey]
+		_, hasPassword := data[corev1.BasicAuthPasswordKey]
+		if !hasUsername && !hasPassword {
+			allErrs = append(allErrs, field.Required(dataPath.Key(corev1.BasicAuthUsernameKey),
+				fmt.Sprintf("%s or %s is required for type %s", corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey, secretType)))
+		}
+	case corev1.SecretTypeServiceAccountToken:
+		if annotations[corev1.ServiceAccountNameKey] == "" {
+			allErrs = append(allErrs, field.Required(field.NewPath("metadata", "annotations").Key(corev1.ServiceAccountNameKey),
+				fmt.Sprintf("required for type %s", secretType)))
+		}
+	}
+
+	return allErrs.ToAggregate()
+}
+
+// validateConfigMap checks the keys routed to the ConfigMap, which follow the same rules as those of a Secret.
+func validateConfigMap(data map[string]string) error {
+	sizes := make(map[string]int, len(data))
+	for key, value := range data {
+		sizes[key] = len(value)
+	}
+	return validateDataKeys(field.NewPath("configMap", "data"), sizes).ToAggregate()
+}
+
+// validateDataKeys checks the key names and total size of data given the size of every value.
+func validateDataKeys(dataPath *field.Path, sizes map[string]int) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// Sorted so the message is the same on every reconcile.
+	keys := make([]string, 0, len(sizes))
+	for key := range sizes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var totalSize int
+	for _, key := range keys {
+		for _, msg := range validation.IsConfigMapKey(key) {
+			allErrs = append(allErrs, field.Invalid(dataPath.Key(key), key, msg))
+		}
+		totalSize += sizes[key]
+	}
+	// ConfigMaps share the size limit of Secrets.
+	if totalSize > corev1.MaxSecretSize {
+		allErrs = append(allErrs, field.TooLong(dataPath, "", corev1.MaxSecretSize))
+	}
+	return allErrs
+}
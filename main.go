@@ -18,7 +18,10 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -43,11 +46,51 @@ func init() {
 
 func main() {
 	var metricsAddr string
+	var decryptCacheSize int
+	var decryptCacheTTL time.Duration
+	var restrictNamespacedTargets bool
+	var resyncInterval time.Duration
+	var decryptRetryBaseDelay, decryptRetryMaxDelay time.Duration
+	var defaultOwnershipMode string
+	var defaultDeletionPolicy string
+	var orphanPolicy string
+	var orphanInterval time.Duration
+	var orphanDryRun bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.IntVar(&decryptCacheSize, "decrypt-cache-size", 128, "Maximum number of decrypted SopsSecrets kept in memory, 0 disables the cache.")
+	flag.DurationVar(&decryptCacheTTL, "decrypt-cache-ttl", 10*time.Minute, "How long decrypted data is kept in memory before decrypting again.")
+	flag.BoolVar(&restrictNamespacedTargets, "restrict-namespaced-targets", false, "Only allow namespaced SopsSecrets to target their own namespace, use ClusterSopsSecrets for distribution.")
+	flag.DurationVar(&resyncInterval, "resync-interval", 10*time.Minute, "How often objects are reconciled without a watch event to detect drift, 0 disables periodic resync.")
+	flag.DurationVar(&decryptRetryBaseDelay, "decrypt-retry-base-delay", 5*time.Second, "Initial delay before retrying a transient decryption failure, doubled on every failure.")
+	flag.DurationVar(&decryptRetryMaxDelay, "decrypt-retry-max-delay", 5*time.Minute, "Maximum delay before retrying a transient decryption failure.")
+	flag.StringVar(&defaultOwnershipMode, "default-ownership-mode", string(secretsv1beta1.OwnershipModeLabel), "Ownership mode of objects without spec.ownershipMode, Label or OwnerReference.")
+	flag.StringVar(&defaultDeletionPolicy, "default-deletion-policy", string(secretsv1beta1.DeletionPolicyDelete), "Deletion policy of objects without spec.deletionPolicy, Delete, Orphan or OrphanWithLabelRemoval.")
+	flag.StringVar(&orphanPolicy, "orphan-gc-policy", "", "What to do with Secrets whose SopsSecret no longer exists, Report or Delete. Disabled when empty.")
+	flag.DurationVar(&orphanInterval, "orphan-gc-interval", time.Hour, "How often to look for orphaned Secrets after the startup sweep, 0 only sweeps on startup.")
+	flag.BoolVar(&orphanDryRun, "orphan-gc-dry-run", false, "Only log the orphaned Secrets that would be deleted.")
 	flag.Parse()
 
+	// DISABLE_FINALIZERS predates the deletion policies, it keeps every Secret when its object is deleted.
+	if finalizersDisabled, _ := strconv.ParseBool(os.Getenv("DISABLE_FINALIZERS")); finalizersDisabled {
+		defaultDeletionPolicy = string(secretsv1beta1.DeletionPolicyOrphan)
+	}
+
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	ownershipMode := secretsv1beta1.OwnershipMode(defaultOwnershipMode)
+	if ownershipMode != secretsv1beta1.OwnershipModeLabel && ownershipMode != secretsv1beta1.OwnershipModeOwnerReference {
+		setupLog.Error(fmt.Errorf("unknown ownership mode %q", defaultOwnershipMode), "invalid flag", "flag", "default-ownership-mode")
+		os.Exit(1)
+	}
+
+	deletionPolicy := secretsv1beta1.DeletionPolicy(defaultDeletionPolicy)
+	switch deletionPolicy {
+	case secretsv1beta1.DeletionPolicyDelete, secretsv1beta1.DeletionPolicyOrphan, secretsv1beta1.DeletionPolicyOrphanWithLabelRemoval:
+	default:
+		setupLog.Error(fmt.Errorf("unknown deletion policy %q", defaultDeletionPolicy), "invalid flag", "flag", "default-deletion-policy")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:             scheme,
 		MetricsBindAddress: metricsAddr,
@@ -57,14 +100,61 @@ func main() {
 		os.Exit(1)
 	}
 
+	plaintextCache := controllers.NewPlaintextCache(decryptCacheSize, decryptCacheTTL)
 	if err = (&controllers.SopsSecretReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("SopsSecret"),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("SopsSecret"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("sops-converter"),
+
+		PlaintextCache:            plaintextCache,
+		RestrictNamespacedTargets: restrictNamespacedTargets,
+		ResyncInterval:            resyncInterval,
+		DecryptRetryBaseDelay:     decryptRetryBaseDelay,
+		DecryptRetryMaxDelay:      decryptRetryMaxDelay,
+		DefaultOwnershipMode:      ownershipMode,
+		DefaultDeletionPolicy:     deletionPolicy,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SopsSecret")
 		os.Exit(1)
 	}
+	if err = (&controllers.ClusterSopsSecretReconciler{
+		SopsSecretReconciler: controllers.SopsSecretReconciler{
+			Client:   mgr.GetClient(),
+			Log:      ctrl.Log.WithName("controllers").WithName("ClusterSopsSecret"),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("sops-converter"),
+
+			PlaintextCache:        plaintextCache,
+			ResyncInterval:        resyncInterval,
+			DecryptRetryBaseDelay: decryptRetryBaseDelay,
+			DecryptRetryMaxDelay:  decryptRetryMaxDelay,
+			DefaultOwnershipMode:  ownershipMode,
+			DefaultDeletionPolicy: deletionPolicy,
+		},
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterSopsSecret")
+		os.Exit(1)
+	}
+	switch controllers.OrphanPolicy(orphanPolicy) {
+	case "":
+	case controllers.OrphanPolicyReport, controllers.OrphanPolicyDelete:
+		if err = mgr.Add(&controllers.OrphanCollector{
+			Client:    mgr.GetClient(),
+			APIReader: mgr.GetAPIReader(),
+			Log:       ctrl.Log.WithName("orphans"),
+			Recorder:  mgr.GetEventRecorderFor("sops-converter"),
+			Policy:    controllers.OrphanPolicy(orphanPolicy),
+			Interval:  orphanInterval,
+			DryRun:    orphanDryRun,
+		}); err != nil {
+			setupLog.Error(err, "unable to add orphaned secret collector")
+			os.Exit(1)
+		}
+	default:
+		setupLog.Error(fmt.Errorf("unknown orphan policy %q", orphanPolicy), "invalid flag", "flag", "orphan-gc-policy")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")